@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+// cacheKey names the cache files of the database and schemas cfg points to.
+// Passwords are left out, they don't change what the schema is.
+func cacheKey(cfg *Config) string {
+	c := cfg.Connection
+	data, _ := json.Marshal([]interface{}{c.Dialect, c.Catalog, c.DSN, c.Service, c.Host, c.Port, c.User, c.Database, cfg.Schemas})
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// cachedSource returns the schema of db from the -cache directory if it was
+// cached for the current schema hash, and introspects and caches it
+// otherwise. Either way db is closed, the schema is all generation needs, so
+// an unchanged database costs a single query.
+func cachedSource(ctx context.Context, cfg *Config, db *introspect.Introspector) (introspect.Source, error) {
+	defer db.Close()
+	hash, err := db.SchemaHash(ctx, cfg.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(cfg)
+	path := filepath.Join(cfg.Cache, key+"-"+hash+".json")
+	schema, err := introspect.LoadSnapshot(path)
+	if err == nil {
+		report.Debug.Printf("schema hash %s unchanged, using %s", hash, path)
+		return schema.Source(), nil
+	}
+	if !os.IsNotExist(err) {
+		report.Debug.Printf("ignoring unreadable cache: %v", err)
+	}
+
+	if schema, err = introspect.Snapshot(ctx, db, cfg.Connection.Dialect, cfg.Schemas); err != nil {
+		return nil, err
+	}
+	if err := writeCache(path, key, schema); err != nil {
+		return nil, err
+	}
+	report.Debug.Printf("cached schema hash %s in %s", hash, path)
+	return schema.Source(), nil
+}
+
+// writeCache writes schema to path and removes what was cached for older
+// schema hashes of the same key.
+func writeCache(path, key string, schema *introspect.Schema) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	old, err := filepath.Glob(filepath.Join(dir, key+"-*.json"))
+	if err != nil {
+		return err
+	}
+	for _, name := range old {
+		if name != path {
+			os.Remove(name)
+		}
+	}
+	// concurrent runs, e.g. pre-commit hooks, never read half a file
+	tmp, err := ioutil.TempFile(dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := introspect.WriteSnapshot(tmp, schema, "json"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,139 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+type AvroSchema struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Fields    []AvroField `json:"fields"`
+}
+
+type AvroField struct {
+	Name string `json:"name"`
+	// Doc holds the column name where Name had to differ from it.
+	Doc     string          `json:"doc,omitempty"`
+	Type    interface{}     `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type AvroLogicalType struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+type AvroArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+// avroTypes uses plain Avro logical types instead of Debezium's semantic
+// io.debezium.time types. Numeric, json and enum values are carried as strings,
+// matching decimal.handling.mode=string.
+var avroTypes = map[string]interface{}{
+	"bool":        "boolean",
+	"int2":        "int",
+	"int4":        "int",
+	"int8":        "long",
+	"float4":      "float",
+	"float8":      "double",
+	"numeric":     "string",
+	"varchar":     "string",
+	"text":        "string",
+	"bpchar":      "string",
+	"tsvector":    "string",
+	"tsquery":     "string",
+	"json":        "string",
+	"jsonb":       "string",
+	"bytea":       "bytes",
+	"uuid":        AvroLogicalType{"string", "uuid"},
+	"date":        AvroLogicalType{"int", "date"},
+	"timestamp":   AvroLogicalType{"long", "timestamp-micros"},
+	"timestamptz": AvroLogicalType{"long", "timestamp-micros"},
+}
+
+// avroName returns name with what Avro names can't hold, anything but
+// [A-Za-z0-9_] and a leading digit, replaced by underscores.
+func avroName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func avroType(col introspect.DBColumn, enums introspect.DBEnums) (interface{}, error) {
+	udt, isArray := elementType(col.UDTName)
+
+	t, ok := avroTypes[udt]
+	if _, isEnum := enums[udt]; isEnum {
+		t, ok = "string", true
+	}
+	if !ok {
+		return nil, fmt.Errorf("avro type not detected for %s", col.UDTName)
+	}
+	if isArray {
+		t = AvroArray{Type: "array", Items: t}
+	}
+	return t, nil
+}
+
+// RenderAvro renders a record schema per model. Nullable columns become a
+// union with null defaulting to null, as Debezium emits them. Names are made
+// valid Avro names, fields renamed doing so keep their column name in doc.
+func RenderAvro(models []Model, enums introspect.DBEnums) ([]OutputFile, error) {
+	files := make([]OutputFile, 0, len(models))
+	names := tableFileNames(models, ".avsc")
+	for i, model := range models {
+		report.Steps.Add(1)
+		schema := AvroSchema{
+			Type:      "record",
+			Name:      avroName(model.Name),
+			Namespace: "models",
+		}
+		taken := make(map[string]bool, len(model.Fields))
+		for _, field := range model.Fields {
+			t, err := avroType(field.Column, enums)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", model.Key(), field.Column.ColumnName, err)
+			}
+			name := avroName(field.Column.ColumnName)
+			for n := 2; taken[name]; n++ {
+				name = avroName(field.Column.ColumnName) + "_" + strconv.Itoa(n)
+			}
+			taken[name] = true
+			avroField := AvroField{Name: name, Type: t}
+			if name != field.Column.ColumnName {
+				avroField.Doc = field.Column.ColumnName
+			}
+			if field.Column.IsNullable {
+				avroField.Type = []interface{}{"null", t}
+				avroField.Default = json.RawMessage("null")
+			}
+			schema.Fields = append(schema.Fields, avroField)
+		}
+
+		content, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, OutputFile{
+			Name:    names[i],
+			Content: append(content, '\n'),
+		})
+	}
+	return files, nil
+}
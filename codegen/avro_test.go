@@ -0,0 +1,52 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+func TestRenderAvro(t *testing.T) {
+	s, err := introspect.ParseDDL(`
+		CREATE TABLE users (id integer PRIMARY KEY);
+		CREATE TABLE billing.users (
+			id integer PRIMARY KEY,
+			"2fa" bool NOT NULL,
+			"full name" text,
+			"full-name" text
+		);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := BuildModels(s.Tables, typemap.Fallback("string"))
+	NameModels(models, NamingOptions{Singular: true})
+	files, err := RenderAvro(models, s.Enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]string, len(files))
+	for _, file := range files {
+		byName[file.Name] = string(file.Content)
+	}
+	if _, ok := byName["users.avsc"]; !ok {
+		t.Errorf("no users.avsc in %v", byName)
+	}
+	billing, ok := byName["billing.users.avsc"]
+	if !ok {
+		t.Fatalf("no billing.users.avsc in %v", byName)
+	}
+	for _, want := range []string{
+		`"name": "_2fa",
+      "doc": "2fa",`,
+		`"name": "full_name",
+      "doc": "full name",`,
+		`"name": "full_name_2",
+      "doc": "full-name",`,
+	} {
+		if !strings.Contains(billing, want) {
+			t.Errorf("no %s in\n%s", want, billing)
+		}
+	}
+}
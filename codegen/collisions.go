@@ -0,0 +1,142 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+// goBuiltins are the predeclared identifiers of Go, which generated
+// parameters and variables must not shadow.
+var goBuiltins = map[string]bool{
+	"any": true, "append": true, "bool": true, "byte": true, "cap": true, "clear": true,
+	"close": true, "comparable": true, "complex": true, "complex64": true, "complex128": true,
+	"copy": true, "delete": true, "error": true, "false": true, "float32": true, "float64": true,
+	"imag": true, "int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"iota": true, "len": true, "make": true, "max": true, "min": true, "new": true, "nil": true,
+	"panic": true, "print": true, "println": true, "real": true, "recover": true, "rune": true,
+	"string": true, "true": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true,
+}
+
+// resolveCollisions returns models renamed where their names would clash in
+// the generated package: models named like an enum, an enum constant, a JSON
+// struct or the constructor or DTO of another model, and fields named like a
+// method generated on their model. Both get a "_" appended, table_names and
+// field_names give them better names.
+func resolveCollisions(models []Model, enums []Enum, opts GoOptions) []Model {
+	declared := make(map[string]bool)
+	for _, enum := range enums {
+		declared[enum.Name] = true
+		for _, value := range enum.Values {
+			declared[value.Name] = true
+		}
+	}
+	for _, model := range models {
+		for _, s := range jsonStructs(model) {
+			declared[s.Name] = true
+		}
+		if opts.DTO {
+			declared[model.Name+"DTO"] = true
+			declared[model.Name+"FromDTO"] = true
+		}
+		if opts.Constructors {
+			declared["New"+model.Name] = true
+		}
+	}
+	if opts.UpdateHelpers {
+		declared["Execer"] = true
+		declared["ErrStaleVersion"] = true
+	}
+	if opts.NextvalHelpers {
+		declared["Querier"] = true
+	}
+	if opts.Registry {
+		declared["Registry"] = true
+		declared["RegistryEntry"] = true
+	}
+	if opts.Meta {
+		declared["ModelMeta"] = true
+		declared["ColumnMeta"] = true
+	}
+	methods := make(map[string]bool)
+	if opts.DTO {
+		methods["ToDTO"] = true
+	}
+	if opts.Stringer {
+		methods["String"] = true
+	}
+	if opts.Clone {
+		methods["Clone"] = true
+	}
+	if opts.UpdateHelpers {
+		methods["Update"] = true
+	}
+	if opts.Meta {
+		methods["Meta"] = true
+	}
+
+	out := make([]Model, len(models))
+	for i, model := range models {
+		name := model.Name
+		for declared[model.Name] {
+			model.Name += "_"
+		}
+		if model.Name != name {
+			report.Debug.Printf("table %s: model %s would clash, naming it %s", model.Key(), name, model.Name)
+			declared[model.Name] = true
+		}
+
+		joins := make(map[string]bool)
+		if opts.JoinHelpers {
+			for _, join := range model.Joins() {
+				joins[join.Method] = true
+			}
+		}
+		var fields []Field
+		for j, field := range model.Fields {
+			if !methods[field.Name] && !joins[field.Name] {
+				continue
+			}
+			if fields == nil {
+				fields = append([]Field(nil), model.Fields...)
+			}
+			fields[j].Name += "_"
+			report.Debug.Printf("column %s.%s: field %s would clash with a method, naming it %s", model.Key(), field.Column.ColumnName, field.Name, fields[j].Name)
+		}
+		if fields != nil {
+			model.Fields = fields
+		}
+		out[i] = model
+	}
+	return out
+}
+
+// retargetRelations returns models with their relations pointing to the names
+// resolveCollisions gave the models they relate to, by "schema.table".
+// Qualified targets in other packages keep their package.
+func retargetRelations(models []Model, names map[string]string) []Model {
+	out := make([]Model, len(models))
+	for i, model := range models {
+		var relations []Relation
+		for j, rel := range model.Relations {
+			name, ok := names[rel.Schema+"."+rel.TableName]
+			if !ok {
+				continue
+			}
+			qualifier := rel.Model[:strings.LastIndex(rel.Model, ".")+1]
+			if rel.Model == qualifier+name {
+				continue
+			}
+			if relations == nil {
+				relations = append([]Relation(nil), model.Relations...)
+			}
+			relations[j].Model = qualifier + name
+		}
+		if relations != nil {
+			model.Relations = relations
+		}
+		out[i] = model
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package codegen
+
+import (
+	"path"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+// ApplyColumnDirectives applies the directives in column comments, words
+// starting with @ among the rest of the comment:
+//
+//	@gotype:decimal.Decimal  the field's type, an import path may lead the
+//	                         package, as in github.com/shopspring/decimal.Decimal
+//	@json:-                  leave the field out of JSON, @json:name renames it
+//	@sensitive               redact the field in String()
+//
+// It runs before ApplyColumnTypes, so that configured types win.
+func ApplyColumnDirectives(models []Model) {
+	for i, model := range models {
+		for j, field := range model.Fields {
+			if field.Column.Comment == nil {
+				continue
+			}
+			f := &models[i].Fields[j]
+			for _, word := range strings.Fields(*field.Column.Comment) {
+				if !strings.HasPrefix(word, "@") {
+					continue
+				}
+				name, value := word[1:], ""
+				if k := strings.IndexByte(name, ':'); k >= 0 {
+					name, value = name[:k], name[k+1:]
+				}
+				switch {
+				case name == "gotype" && value != "":
+					f.Type, f.Import = value, ""
+					base := strings.TrimLeft(value, "*[]")
+					if k := strings.LastIndexByte(base, '/'); k >= 0 {
+						if dot := strings.LastIndexByte(base[k:], '.'); dot >= 0 {
+							f.Import = base[:k+dot]
+							f.Type = value[:len(value)-len(base)] + path.Base(f.Import) + base[k+dot:]
+						}
+					}
+					if field.Nullable {
+						f.Type = "*" + f.Type
+					}
+				case name == "json" && value != "":
+					f.JSONName = value
+				case name == "sensitive" && value == "":
+					f.Sensitive = true
+				default:
+					report.Debug.Printf("column %s.%s: ignoring directive %s", model.Key(), field.Column.ColumnName, word)
+				}
+			}
+		}
+	}
+}
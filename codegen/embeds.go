@@ -0,0 +1,123 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+const embedTpl = `
+// {{.Name}} holds columns several models share.
+type {{.Name}} struct {
+{{range .Fields}}{{with .Note}}	// {{.}}
+{{end}}	{{.Name}} {{.Type}} ` + "`{{.Tag}}`" + `
+{{end}}}
+`
+
+// EmbedOptions is a set of columns, such as created_at and updated_at, that
+// models having all of them embed as a shared struct instead of repeating
+// the fields.
+type EmbedOptions struct {
+	Name    string   `yaml:"name"`
+	Columns []string `yaml:"columns"`
+}
+
+// timestampsEmbed is what EmbedTimestamps embeds.
+var timestampsEmbed = EmbedOptions{Name: "Timestamps", Columns: []string{"created_at", "updated_at"}}
+
+// findEmbeds returns models with the configured column sets that at least
+// two of them have, with the same Go types and tags, embedded, and the
+// structs they embed. A column is embedded once, the first matching set wins.
+func findEmbeds(models []Model, enums []Enum, opts GoOptions) ([]Model, []Model) {
+	sets := opts.Embeds
+	if opts.EmbedTimestamps {
+		sets = append([]EmbedOptions{timestampsEmbed}, sets...)
+	}
+	if len(sets) == 0 {
+		return models, nil
+	}
+	names := make(map[string]bool, len(models))
+	for _, model := range models {
+		names[model.Name] = true
+	}
+	for _, enum := range enums {
+		names[enum.Name] = true
+	}
+
+	out := append([]Model(nil), models...)
+	var embeds []Model
+	for _, set := range sets {
+		if names[set.Name] {
+			report.Debug.Printf("not embedding %s, a model or enum has that name", set.Name)
+			continue
+		}
+		var (
+			ref     []Field
+			refSig  string
+			matched []int
+		)
+		for i, model := range out {
+			fields, ok := embedFields(model, set.Name, set.Columns)
+			if !ok {
+				continue
+			}
+			sig := fieldsSignature(fields)
+			if ref == nil {
+				ref, refSig = fields, sig
+			}
+			if sig == refSig {
+				matched = append(matched, i)
+			} else {
+				report.Debug.Printf("table %s: not embedding %s, its columns differ in type", model.Key(), set.Name)
+			}
+		}
+		if len(matched) < 2 {
+			continue
+		}
+		embeds = append(embeds, Model{Name: set.Name, Fields: ref})
+		for _, i := range matched {
+			fields := append([]Field(nil), out[i].Fields...)
+			for j := range fields {
+				if matchColumn(set.Columns, "", fields[j].Column.ColumnName) {
+					fields[j].Embed = set.Name
+				}
+			}
+			out[i].Fields = fields
+			out[i].Embeds = append(append([]string(nil), out[i].Embeds...), set.Name)
+		}
+	}
+	return out, embeds
+}
+
+// embedFields returns the fields of model for columns, in their order, and
+// whether it has all of them, none is embedded already and no field is named
+// like the struct.
+func embedFields(model Model, name string, columns []string) ([]Field, bool) {
+	for _, field := range model.Fields {
+		if field.Name == name {
+			return nil, false
+		}
+	}
+	fields := make([]Field, 0, len(columns))
+	for _, column := range columns {
+		found := false
+		for _, field := range model.Fields {
+			if field.Column.ColumnName == column && field.Embed == "" {
+				fields, found = append(fields, field), true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return fields, true
+}
+
+func fieldsSignature(fields []Field) string {
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(field.Name + " " + field.Type + " " + field.Tag + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,230 @@
+package codegen
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+const enumTpl = `
+type {{.Name}} string
+
+const (
+{{range .Values}}	{{.Name}} {{$.Name}} = {{printf "%q" .Label}}
+{{end}})
+
+func (e {{.Name}}) IsValid() bool {
+	switch e {
+	case {{range $i, $v := .Values}}{{if $i}}, {{end}}{{$v.Name}}{{end}}:
+		return true
+	}
+	return false
+}
+
+func (e *{{.Name}}) Scan(src interface{}) error {
+	var label string
+	switch v := src.(type) {
+	case string:
+		label = v
+	case []byte:
+		label = string(v)
+	default:
+		return fmt.Errorf("{{.Name}}: cannot scan %T", src)
+	}
+	if !{{.Name}}(label).IsValid() {
+		return fmt.Errorf("{{.Name}}: invalid value %q", label)
+	}
+	*e = {{.Name}}(label)
+	return nil
+}
+
+func (e {{.Name}}) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("{{.Name}}: invalid value %q", string(e))
+	}
+	return string(e), nil
+}
+
+func (e {{.Name}}) MarshalJSON() ([]byte, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("{{.Name}}: invalid value %q", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+func (e *{{.Name}}) UnmarshalJSON(data []byte) error {
+	var label string
+	if err := json.Unmarshal(data, &label); err != nil {
+		return err
+	}
+	if !{{.Name}}(label).IsValid() {
+		return fmt.Errorf("{{.Name}}: invalid value %q", label)
+	}
+	*e = {{.Name}}(label)
+	return nil
+}
+`
+
+// enumTextTpl makes enums usable in URLs, env parsing and as JSON map keys.
+const enumTextTpl = `
+func (e {{.Name}}) MarshalText() ([]byte, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("{{.Name}}: invalid value %q", string(e))
+	}
+	return []byte(e), nil
+}
+
+func (e *{{.Name}}) UnmarshalText(text []byte) error {
+	if !{{.Name}}(text).IsValid() {
+		return fmt.Errorf("{{.Name}}: invalid value %q", text)
+	}
+	*e = {{.Name}}(text)
+	return nil
+}
+`
+
+type Enum struct {
+	Name     string
+	TypeName string
+	Values   []EnumValue
+}
+
+type EnumValue struct {
+	Name  string
+	Label string
+}
+
+// EnumNaming controls the names of enum constants.
+type EnumNaming struct {
+	// Style is "prefixed", the type name followed by the label
+	// (MoodInProgress), "caps" (MOOD_IN_PROGRESS) or "camel", the label alone
+	// (InProgress).
+	Style string
+	// Names are constant names by "type.label", taking precedence.
+	Names map[string]string
+}
+
+// Enums converts enum types to their Go form ordered by type name. Labels
+// are split into words at every run of non-alphanumeric characters. Camel
+// case constants clashing with each other or a type are prefixed after all,
+// constants clashing still are numbered.
+func Enums(enums introspect.DBEnums, naming EnumNaming) []Enum {
+	result := make([]Enum, 0, len(enums))
+	declared := make(map[string]int)
+	for typeName, labels := range enums {
+		enum := Enum{
+			Name:     toCamelCase(typeName),
+			TypeName: typeName,
+		}
+		declared[enum.Name]++
+		for i, label := range labels {
+			name := enumConstName(enum, label, i, naming)
+			declared[name]++
+			enum.Values = append(enum.Values, EnumValue{
+				Name:  name,
+				Label: label,
+			})
+		}
+		result = append(result, enum)
+	}
+	for _, enum := range result {
+		for i, value := range enum.Values {
+			_, named := naming.Names[enum.TypeName+"."+value.Label]
+			if naming.Style != "camel" || named || declared[value.Name] < 2 {
+				continue
+			}
+			enum.Values[i].Name = enumConstName(enum, value.Label, i, EnumNaming{})
+			report.Debug.Printf("enum %s: constant %s of %q would clash, naming it %s", enum.TypeName, value.Name, value.Label, enum.Values[i].Name)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TypeName < result[j].TypeName
+	})
+
+	// labels differing in punctuation alone, like in-progress and
+	// in_progress, get the same name in every style, later ones are numbered
+	taken := make(map[string]bool, len(declared))
+	for _, enum := range result {
+		taken[enum.Name] = true
+		for _, value := range enum.Values {
+			if _, named := naming.Names[enum.TypeName+"."+value.Label]; named {
+				taken[value.Name] = true
+			}
+		}
+	}
+	sep := ""
+	if naming.Style == "caps" {
+		sep = "_"
+	}
+	for _, enum := range result {
+		for i, value := range enum.Values {
+			if _, named := naming.Names[enum.TypeName+"."+value.Label]; named {
+				continue
+			}
+			name := value.Name
+			for n := 2; taken[name]; n++ {
+				name = value.Name + sep + strconv.Itoa(n)
+			}
+			taken[name] = true
+			if name != value.Name {
+				enum.Values[i].Name = name
+				report.Debug.Printf("enum %s: constant %s of %q would clash, naming it %s", enum.TypeName, value.Name, value.Label, name)
+			}
+		}
+	}
+	return result
+}
+
+// enumConstName names the constant of label, the i-th of enum, see
+// EnumNaming.
+func enumConstName(enum Enum, label string, i int, naming EnumNaming) string {
+	if name, ok := naming.Names[enum.TypeName+"."+label]; ok {
+		return name
+	}
+	// labels of symbols alone, like "+", have no words to name them by
+	number := strconv.Itoa(i + 1)
+	switch naming.Style {
+	case "caps":
+		words := capsWords(label)
+		if words == "" {
+			words = "VALUE_" + number
+		}
+		return capsWords(enum.TypeName) + "_" + words
+	case "camel":
+		// labels starting with a digit are spelled like other names
+		if name := toCamelCase(label); name != "" {
+			return name
+		}
+	}
+	words := joinWords(label)
+	if words == "" {
+		words = "Value" + number
+	}
+	return enum.Name + words
+}
+
+// capsWords joins the words of in in upper case with underscores.
+func capsWords(in string) string {
+	var words []string
+	parts := strings.FieldsFunc(asciiFold(in), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, part := range parts {
+		for _, word := range splitWords(part) {
+			words = append(words, strings.ToUpper(word))
+		}
+	}
+	return strings.Join(words, "_")
+}
+
+// AddEnums maps every enum type, and arrays of it, to its generated Go type.
+func AddEnums(tm *typemap.TypesMapping, enums []Enum) {
+	for _, enum := range enums {
+		tm.AddEnum(enum.TypeName, enum.Name)
+	}
+}
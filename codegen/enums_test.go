@@ -0,0 +1,56 @@
+package codegen
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+func TestEnumConstNames(t *testing.T) {
+	enums := introspect.DBEnums{
+		"status": {"in-progress", "in_progress", "+", "-", "2xx", "status"},
+		"kind":   {"status", "a"},
+	}
+	tests := []struct {
+		style string
+		want  map[string][]string
+	}{
+		{
+			style: "prefixed",
+			want: map[string][]string{
+				"kind":   {"KindStatus", "KindA"},
+				"status": {"StatusInProgress", "StatusInProgress2", "StatusValue3", "StatusValue4", "Status2xx", "StatusStatus"},
+			},
+		},
+		{
+			style: "caps",
+			want: map[string][]string{
+				"kind":   {"KIND_STATUS", "KIND_A"},
+				"status": {"STATUS_IN_PROGRESS", "STATUS_IN_PROGRESS_2", "STATUS_VALUE_3", "STATUS_VALUE_4", "STATUS_2XX", "STATUS_STATUS"},
+			},
+		},
+		{
+			style: "camel",
+			want: map[string][]string{
+				"kind":   {"KindStatus", "A"},
+				"status": {"StatusInProgress", "StatusInProgress2", "StatusValue3", "StatusValue4", "TwoXx", "StatusStatus"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			result := Enums(enums, EnumNaming{Style: tt.style})
+			got := make(map[string][]string)
+			for _, enum := range result {
+				for _, value := range enum.Values {
+					got[enum.TypeName] = append(got[enum.TypeName], value.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %v\nwant %v", got, tt.want)
+			}
+			renderTestGo(t, nil, result, GoOptions{})
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package codegen
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"text/template"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const (
+	mermaidTpl = `erDiagram
+{{range .Models}}{{$m := .}}    {{.TableName}} {
+{{range .Fields}}        {{sqlType .Column}} {{.Column.ColumnName}}{{if isFK $.Keys $m.TableName .Column.ColumnName}} FK{{end}}
+{{end}}    }
+{{end}}{{range .Models}}{{$m := .}}{{range .Relations}}{{if not .Many}}    {{.TableName}} {{if .Nullable}}|o{{else}}||{{end}}--o{ {{$m.TableName}} : "{{join .Columns ", "}}"
+{{end}}{{end}}{{end}}`
+
+	dotTpl = `digraph erd {
+    rankdir=LR;
+    node [shape=plaintext];
+{{range .Models}}
+    {{dotID .TableName}} [label=<<table border="0" cellborder="1" cellspacing="0">
+        <tr><td bgcolor="lightgrey"><b>{{html .TableName}}</b></td></tr>
+{{range .Fields}}        <tr><td port="{{html .Column.ColumnName}}" align="left">{{html .Column.ColumnName}}: {{html (sqlType .Column)}}</td></tr>
+{{end}}    </table>>];
+{{end}}
+{{range .Models}}{{$m := .}}{{range .Relations}}{{if not .Many}}    {{dotID $m.TableName}}:{{dotID (index .Columns 0)}} -> {{dotID .TableName}}:{{dotID (index .RefColumns 0)}};
+{{end}}{{end}}{{end}}}
+`
+)
+
+type ERD struct {
+	Models []Model
+	Keys   map[string]bool
+}
+
+// sqlType formats the column type the way psql shows it, e.g. text[] for _text.
+func sqlType(col introspect.DBColumn) string {
+	if strings.HasPrefix(col.UDTName, "_") {
+		return col.UDTName[1:] + "[]"
+	}
+	return col.UDTName
+}
+
+// dotID quotes id as a DOT ID, which keeps names like node or order-items
+// from being read as keywords or operators.
+func dotID(id string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(id) + `"`
+}
+
+// RenderERD renders tables and belongs-to relations as a mermaid erDiagram or
+// a graphviz digraph, depending on style.
+func RenderERD(models []Model, style string) ([]byte, error) {
+	erd := ERD{
+		Models: models,
+		Keys:   make(map[string]bool),
+	}
+	for _, model := range models {
+		for _, rel := range model.Relations {
+			if rel.Many {
+				continue
+			}
+			for _, col := range rel.Columns {
+				erd.Keys[model.TableName+"."+col] = true
+			}
+		}
+	}
+
+	text := mermaidTpl
+	if style == "dot" {
+		text = dotTpl
+	}
+	tmpl, err := template.New("erd").Funcs(template.FuncMap{
+		"sqlType": sqlType,
+		"join":    strings.Join,
+		"html":    html.EscapeString,
+		"dotID":   dotID,
+		"isFK": func(keys map[string]bool, table, column string) bool {
+			return keys[table+"."+column]
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, erd); err != nil {
+		return nil, err
+	}
+	report.Steps.Add(len(models))
+	return buf.Bytes(), nil
+}
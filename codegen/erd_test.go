@@ -0,0 +1,35 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+func TestRenderERDDot(t *testing.T) {
+	s, err := introspect.ParseDDL(`
+		CREATE TABLE node (id integer PRIMARY KEY);
+		CREATE TABLE "order-items" (id integer PRIMARY KEY, "node-id" integer REFERENCES node (id));`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := BuildModels(s.Tables, typemap.Fallback("string"))
+	NameModels(models, NamingOptions{Singular: true})
+	LinkRelations(models, s.ForeignKeys)
+	content, err := RenderERD(models, "dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`    "node" [label=<`,
+		`    "order-items" [label=<`,
+		`<td port="node-id" align="left">`,
+		`    "order-items":"node-id" -> "node":"id";`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("no %s in\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,812 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/token"
+	"path"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/imports"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+const (
+	headerTpl = `{{with .BuildTag}}//go:build {{.}}
+
+{{end}}package {{.Package}}
+
+import (
+{{range .Imports}}	"{{.}}"
+{{end}})
+`
+
+	modelTpl = "{{with .RowSecurity}}// {{$.Name}} rows are filtered by row level security{{if .Forced}}, for the table owner too{{end}}:\n{{range .Policies}}//   - {{.}}\n{{else}}//   - no policies, no rows are visible\n{{end}}{{if $.Triggers}}//\n{{end}}{{end}}{{with .Triggers}}// {{$.Name}} rows fire triggers:\n{{range .}}//   - {{.}}\n{{end}}{{end}}type {{.Name}} struct {\ntableName struct{} `sql:\"{{.TagSQLName}}\"`\n{{range .Fields}}{{if not .Embed}}{{with .Note}}\t// {{.}}\n{{end}}\t{{.Name}} {{.Type}} `{{.Tag}}`\n{{end}}{{end}}{{range .Embeds}}\t{{.}}\n{{end}}{{range .Relations}}{{if .Field}}\t{{.FieldName}} []*{{.Model}} `{{.Tag}}`\n{{end}}{{end}} }\n\n"
+
+	dtoTpl = `
+type {{.Name}}DTO struct {
+{{range .Fields}}{{if .InDTO}}	{{.Name}} {{.Type}} ` + "`" + `json:"{{.JSONName}}{{if .Nullable}},omitempty{{end}}"{{with .Validate}} validate:"{{.}}"{{end}}` + "`" + `
+{{end}}{{end}}}
+
+func (m *{{.Name}}) ToDTO() *{{.Name}}DTO {
+	if m == nil {
+		return nil
+	}
+	return &{{.Name}}DTO{
+{{range .Fields}}{{if .InDTO}}		{{.Name}}: m.{{.Name}},
+{{end}}{{end}}	}
+}
+
+func {{.Name}}FromDTO(dto *{{.Name}}DTO) *{{.Name}} {
+	if dto == nil {
+		return nil
+	}
+	return &{{.Name}}{
+{{range .Fields}}{{if and .InDTO (not .Embed)}}		{{.Name}}: dto.{{.Name}},
+{{end}}{{end}}{{range $e := .Embeds}}		{{$e}}: {{$e}}{
+{{range $.Fields}}{{if and .InDTO (eq .Embed $e)}}			{{.Name}}: dto.{{.Name}},
+{{end}}{{end}}		},
+{{end}}	}
+}
+
+`
+
+	stringerTpl = `
+func (m *{{.Name}}) String() string {
+	if m == nil {
+		return "<nil>"
+	}
+	var b strings.Builder
+	b.WriteString("{{.Name}}{")
+{{range $i, $f := .Fields}}{{$sep := ""}}{{if $i}}{{$sep = ", "}}{{end}}{{if $f.Redacted}}	b.WriteString("{{$sep}}{{$f.Name}}: [REDACTED]")
+{{else if $f.Pointer}}	if m.{{$f.Name}} != nil {
+		fmt.Fprintf(&b, "{{$sep}}{{$f.Name}}: %v", *m.{{$f.Name}})
+	} else {
+		b.WriteString("{{$sep}}{{$f.Name}}: <nil>")
+	}
+{{else}}	fmt.Fprintf(&b, "{{$sep}}{{$f.Name}}: %v", m.{{$f.Name}})
+{{end}}{{end}}	b.WriteString("}")
+	return b.String()
+}
+`
+
+	constructorTpl = `
+func New{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}}) *{{.Name}} {
+	return &{{.Name}}{
+{{range .Params}}{{if not .Embed}}		{{.Field}}: {{.Name}},
+{{end}}{{end}}{{range $e := .Embeds}}		{{$e.Name}}: {{$e.Name}}{
+{{range $e.Params}}			{{.Field}}: {{.Name}},
+{{end}}		},
+{{end}}	}
+}
+`
+
+	cloneTpl = `
+func (m *{{.Name}}) Clone() *{{.Name}} {
+	if m == nil {
+		return nil
+	}
+	c := *m
+{{range .Statements}}{{.}}
+{{end}}	return &c
+}
+`
+
+	cloneValueTpl = `
+// cloneValue deep-copies values decoded from json columns.
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			c[key] = cloneValue(value)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, value := range v {
+			c[i] = cloneValue(value)
+		}
+		return c
+	case json.RawMessage:
+		return append(json.RawMessage(nil), v...)
+	case []byte:
+		return append([]byte(nil), v...)
+	}
+	return v
+}
+`
+
+	// code between the markers is kept when the file is regenerated
+	customRegionTpl = `
+// -- custom {{.}} --
+// -- end custom {{.}} --
+`
+)
+
+// GoOptions controls what is generated next to the model structs.
+type GoOptions struct {
+	Package        string   `yaml:"package"`
+	Imports        []string `yaml:"imports"`
+	JSONTags       bool     `yaml:"json_tags"`
+	DBTags         bool     `yaml:"db_tags"`
+	DTO            bool     `yaml:"dto"`
+	Stringer       bool     `yaml:"stringer"`
+	Constructors   bool     `yaml:"constructors"`
+	Clone          bool     `yaml:"clone"`
+	BuildTag       string   `yaml:"build_tag"`
+	SeparateFiles  bool     `yaml:"separate_files"`
+	FileNaming     string   `yaml:"file_naming"`
+	FileSuffix     string   `yaml:"file_suffix"`
+	StringerFields []string `yaml:"stringer_fields"`
+	Sensitive      []string `yaml:"sensitive"`
+	CustomRegions  bool     `yaml:"custom_regions"`
+	// EmbedTimestamps embeds created_at and updated_at as Timestamps.
+	EmbedTimestamps bool `yaml:"embed_timestamps"`
+	// ValidateTags adds validate:"max=N" to fields of length limited columns.
+	ValidateTags bool `yaml:"validate_tags"`
+	// CharNotes comments on char(n) fields that values are blank-padded.
+	CharNotes bool `yaml:"char_notes"`
+	// EnumText implements encoding.TextMarshaler and TextUnmarshaler on enums.
+	EnumText bool `yaml:"enum_text"`
+	// SequenceConstants declares the names of the sequences filling columns,
+	// NextvalHelpers functions returning their next value.
+	SequenceConstants bool `yaml:"sequence_constants"`
+	NextvalHelpers    bool `yaml:"nextval_helpers"`
+	// JoinHelpers adds With<Relation>() methods returning JOIN clauses.
+	JoinHelpers bool `yaml:"join_helpers"`
+	// SchemaPackages generates the models of every schema but public into a
+	// package of its own, below the output directory. ImportPath is the
+	// import path of the output package, read from go.mod if empty.
+	SchemaPackages bool   `yaml:"schema_packages"`
+	ImportPath     string `yaml:"import_path"`
+	// Packages groups tables into packages below the output directory, by
+	// package path and table patterns like include_tables'. They win over
+	// SchemaPackages.
+	Packages map[string][]string `yaml:"packages"`
+	// Registry declares a Registry of the models by table in every package.
+	Registry bool `yaml:"registry"`
+	// Meta adds Meta() methods describing the table of a model.
+	Meta bool `yaml:"meta"`
+	// UpdateHelpers adds database/sql Update methods by primary key.
+	UpdateHelpers bool           `yaml:"update_helpers"`
+	Embeds        []EmbedOptions `yaml:"embeds"`
+}
+
+type StringerField struct {
+	Name     string
+	Pointer  bool
+	Redacted bool
+}
+
+// stringerFields picks the primary key and the configured columns, or every
+// column if there are neither. Column lists match either "column" or
+// "table.column".
+func stringerFields(model Model, opts GoOptions) []StringerField {
+	var picked []Field
+	for _, field := range model.Fields {
+		if field.Column.IsPrimaryKey || matchColumn(opts.StringerFields, model.TableName, field.Column.ColumnName) {
+			picked = append(picked, field)
+		}
+	}
+	if len(picked) == 0 {
+		picked = model.Fields
+	}
+
+	fields := make([]StringerField, 0, len(picked))
+	for _, field := range picked {
+		fields = append(fields, StringerField{
+			Name:     field.Name,
+			Pointer:  strings.HasPrefix(field.Type, "*"),
+			Redacted: field.Sensitive || matchColumn(opts.Sensitive, model.TableName, field.Column.ColumnName),
+		})
+	}
+	return fields
+}
+
+// generatedImports are the package names generated code may refer to.
+var generatedImports = map[string]bool{
+	"context": true,
+	"driver":  true,
+	"errors":  true,
+	"fmt":     true,
+	"json":    true,
+	"sql":     true,
+	"strings": true,
+	"time":    true,
+}
+
+// cloneStatements returns the statements deep-copying every field of c that
+// would otherwise share memory with the original after c := *m, has-many
+// relations included.
+func cloneStatements(model Model) []string {
+	var stmts []string
+	for _, field := range model.Fields {
+		stmts = append(stmts, cloneField("c."+field.Name, "m."+field.Name, field.Type, field.JSONStruct)...)
+	}
+	for _, rel := range model.Relations {
+		if rel.Field {
+			stmts = append(stmts, fmt.Sprintf(
+				"if m.%[1]s != nil {\nc.%[1]s = make([]*%[2]s, len(m.%[1]s))\nfor i, v := range m.%[1]s {\nc.%[1]s[i] = v.Clone()\n}\n}", rel.FieldName, rel.Model))
+		}
+	}
+	return stmts
+}
+
+// cloneField returns the statements setting dst, a copy of src, to a deep
+// copy of src of Go type t, descending into the fields of JSON structs.
+func cloneField(dst, src, t string, js *JSONStruct) []string {
+	pointer := strings.HasPrefix(t, "*")
+	if pointer {
+		t = t[1:]
+	}
+
+	switch {
+	case js != nil:
+		var stmts []string
+		for _, f := range js.Fields {
+			stmts = append(stmts, cloneField(dst+"."+f.Name, src+"."+f.Name, f.Type, nil)...)
+		}
+		if !pointer {
+			return stmts
+		}
+		return []string{fmt.Sprintf("if %[2]s != nil {\n%[1]s = new(%[3]s)\n*%[1]s = *%[2]s\n%[4]s}", dst, src, t, joinLines(stmts))}
+	case strings.HasPrefix(t, "[]") && pointer:
+		return []string{fmt.Sprintf(
+			"if %[2]s != nil {\nv := make(%[3]s, len(*%[2]s))\ncopy(v, *%[2]s)\n%[1]s = &v\n}", dst, src, t)}
+	case strings.HasPrefix(t, "[]") || t == "json.RawMessage":
+		return []string{fmt.Sprintf(
+			"if %[2]s != nil {\n%[1]s = make(%[3]s, len(%[2]s))\ncopy(%[1]s, %[2]s)\n}", dst, src, t)}
+	case strings.HasPrefix(t, "map[") && !pointer:
+		return []string{fmt.Sprintf(
+			"if %[2]s != nil {\n%[1]s = make(%[3]s, len(%[2]s))\nfor k, v := range %[2]s {\n%[1]s[k] = v\n}\n}", dst, src, t)}
+	case t == "interface{}" && pointer:
+		return []string{fmt.Sprintf(
+			"if %[2]s != nil {\nv := cloneValue(*%[2]s)\n%[1]s = &v\n}", dst, src)}
+	case t == "interface{}":
+		return []string{fmt.Sprintf("%[1]s = cloneValue(%[2]s)", dst, src)}
+	case pointer:
+		return []string{fmt.Sprintf(
+			"if %[2]s != nil {\nv := *%[2]s\n%[1]s = &v\n}", dst, src)}
+	}
+	return nil
+}
+
+// joinLines joins stmts into lines, each ending in a newline.
+func joinLines(stmts []string) string {
+	var b strings.Builder
+	for _, stmt := range stmts {
+		b.WriteString(stmt + "\n")
+	}
+	return b.String()
+}
+
+// withTags returns a copy of model with the optional json, db and validate
+// tags and notes added to its fields, and the fields go.sensitive lists
+// marked Sensitive.
+func withTags(model Model, opts GoOptions) Model {
+	fields := make([]Field, len(model.Fields))
+	for i, field := range model.Fields {
+		if opts.JSONTags {
+			field.Tag += fmt.Sprintf(` json:"%s"`, field.JSONName)
+			if field.Nullable && field.JSONName != "-" {
+				field.Tag = strings.TrimSuffix(field.Tag, `"`) + `,omitempty"`
+			}
+		}
+		if opts.DBTags {
+			field.Tag += fmt.Sprintf(` db:"%s"`, tagEscape(field.Column.ColumnName))
+		}
+		if matchColumn(opts.Sensitive, model.TableName, field.Column.ColumnName) {
+			field.Sensitive = true
+		}
+		if !opts.ValidateTags {
+			field.Validate = ""
+		} else if field.Validate != "" {
+			field.Tag += fmt.Sprintf(` validate:"%s"`, field.Validate)
+		}
+		if n := field.Column.CharacterMaximumLength; opts.CharNotes && field.Column.UDTName == "bpchar" && n != nil {
+			field.Note = fmt.Sprintf("char(%d): read back blank-padded to %d characters, trim trailing spaces before comparing", *n, *n)
+		}
+		fields[i] = field
+	}
+	model.Fields = fields
+	if opts.JSONTags {
+		relations := make([]Relation, len(model.Relations))
+		for i, rel := range model.Relations {
+			if rel.Field {
+				rel.Tag += fmt.Sprintf(` json:"%s,omitempty"`, rel.Name)
+			}
+			relations[i] = rel
+		}
+		model.Relations = relations
+	}
+	return model
+}
+
+type ConstructorParam struct {
+	Name  string
+	Type  string
+	Field string
+	Embed string
+}
+
+// ConstructorEmbed is an embedded struct a constructor sets fields of.
+type ConstructorEmbed struct {
+	Name   string
+	Params []ConstructorParam
+}
+
+// constructorEmbeds groups the params of embedded fields by their struct,
+// composite literals can't set promoted fields directly.
+func constructorEmbeds(model Model, params []ConstructorParam) []ConstructorEmbed {
+	var embeds []ConstructorEmbed
+	for _, name := range model.Embeds {
+		embed := ConstructorEmbed{Name: name}
+		for _, param := range params {
+			if param.Embed == name {
+				embed.Params = append(embed.Params, param)
+			}
+		}
+		if len(embed.Params) > 0 {
+			embeds = append(embeds, embed)
+		}
+	}
+	return embeds
+}
+
+// constructorParams returns the not null columns without a default, which
+// are exactly the values the database can't fill in on insert.
+func constructorParams(model Model) []ConstructorParam {
+	var params []ConstructorParam
+	for _, field := range model.Fields {
+		if field.Nullable || field.Column.ColumnDefault != nil {
+			continue
+		}
+		name := toLowerCamelCase(field.Name)
+		// a parameter called e.g. time or len would shadow the package or
+		// builtin
+		if token.IsKeyword(name) || generatedImports[name] || goBuiltins[name] {
+			name += "_"
+		}
+		params = append(params, ConstructorParam{
+			Name:  name,
+			Type:  field.Type,
+			Field: field.Name,
+			Embed: field.Embed,
+		})
+	}
+	return params
+}
+
+func matchColumn(list []string, table, column string) bool {
+	for _, item := range list {
+		if item == column || item == table+"."+column {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderGo lays the generated code out into a single models.go or, with
+// SeparateFiles, a file per model plus one for enums and shared helpers.
+func RenderGo(models []Model, enums []Enum, opts GoOptions) ([]OutputFile, error) {
+	files, err := GoFiles(models, enums, opts)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(files))
+	for i, file := range files {
+		index[file.Name] = i
+	}
+	err = StreamGo(files, models, enums, opts, func(file OutputFile) error {
+		files[index[file.Name]] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GoFiles returns the files RenderGo lays models out into, without content.
+// The files of models in other packages than the output's are in the
+// package's directory.
+func GoFiles(models []Model, enums []Enum, opts GoOptions) ([]OutputFile, error) {
+	models, err := packageModels(models, enums, opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []OutputFile
+	for _, pkg := range packages(models) {
+		var pkgEnums []Enum
+		if pkg == "" {
+			pkgEnums = enums
+		}
+		pkgFiles, err := goPackageFiles(inPackage(models, pkg), pkgEnums, packageOptions(pkg, opts))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range pkgFiles {
+			file.Name = path.Join(pkg, file.Name)
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// goPackageFiles returns the files of a single package.
+func goPackageFiles(models []Model, enums []Enum, opts GoOptions) ([]OutputFile, error) {
+	if !opts.SeparateFiles {
+		return []OutputFile{{Name: "models" + opts.FileSuffix}}, nil
+	}
+
+	var (
+		files  []OutputFile
+		owners = make(map[string]string)
+	)
+	add := func(name, owner, table string) error {
+		if other, ok := owners[name]; ok {
+			return fmt.Errorf("%s and %s would both be written to %s", other, owner, name)
+		}
+		owners[name] = owner
+		files = append(files, OutputFile{Name: name, Table: table})
+		return nil
+	}
+	_, embeds := findEmbeds(resolveCollisions(models, enums, opts), enums, opts)
+	if len(enums) > 0 || opts.Clone || opts.UpdateHelpers || opts.NextvalHelpers || opts.Registry || opts.Meta || len(embeds) > 0 {
+		if err := add("models"+opts.FileSuffix, "enums", ""); err != nil {
+			return nil, err
+		}
+	}
+	tables := make(map[string]int, len(models))
+	for _, model := range models {
+		tables[model.TableName]++
+	}
+	for _, model := range models {
+		name := goFileName(model, opts)
+		// the same table in another schema
+		if tables[model.TableName] > 1 && model.Schema != "public" {
+			name = model.Schema + "_" + name
+		}
+		if err := add(name, "table "+model.Key(), model.Key()); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// StreamGo renders files, some or all of what GoFiles returns, and hands each
+// to emit as soon as it's rendered, so that with SeparateFiles no more than
+// Workers files are held in memory. emit is called from up to Workers
+// goroutines at once.
+func StreamGo(files []OutputFile, models []Model, enums []Enum, opts GoOptions, emit func(OutputFile) error) error {
+	models, err := packageModels(models, enums, opts)
+	if err != nil {
+		return err
+	}
+	type goPackage struct {
+		models, embeds []Model
+		enums          []Enum
+		opts           GoOptions
+	}
+	pkgs := make(map[string]goPackage)
+	names := make(map[string]string, len(models))
+	for _, pkg := range packages(models) {
+		p := goPackage{opts: packageOptions(pkg, opts)}
+		if pkg == "" {
+			p.enums = enums
+		}
+		p.models = resolveCollisions(inPackage(models, pkg), p.enums, p.opts)
+		for _, model := range p.models {
+			names[model.Key()] = model.Name
+		}
+		pkgs[pkg] = p
+	}
+	// relations of every package follow the models they point to
+	byKey := make(map[string]Model, len(models))
+	for pkg, p := range pkgs {
+		p.models, p.embeds = findEmbeds(retargetRelations(p.models, names), p.enums, p.opts)
+		for _, model := range p.models {
+			byKey[model.Key()] = model
+		}
+		pkgs[pkg] = p
+	}
+	return forEach(len(files), func(i int) error {
+		file := files[i]
+		p := pkgs[filePackage(file.Name)]
+		var err error
+		switch {
+		case !opts.SeparateFiles:
+			file.Content, err = renderGoFile(p.models, p.enums, p.embeds, p.models, p.opts, true)
+		case file.Table == "":
+			file.Content, err = renderGoFile(nil, p.enums, p.embeds, p.models, p.opts, true)
+		default:
+			file.Content, err = renderGoFile([]Model{byKey[file.Table]}, nil, nil, nil, p.opts, false)
+		}
+		if err != nil {
+			return err
+		}
+		return emit(file)
+	})
+}
+
+func goFileName(model Model, opts GoOptions) string {
+	name := model.TableName
+	if opts.FileNaming == "singular" {
+		name = singularize(name)
+	}
+	return name + opts.FileSuffix
+}
+
+// renderGoFile renders models, enums and the structs models embed into a
+// single file. Package level helpers are only emitted when shared is set, so
+// that they are declared once, the registry listing the models of registry.
+func renderGoFile(models []Model, enums []Enum, embeds, registry []Model, opts GoOptions, shared bool) ([]byte, error) {
+	var buffer bytes.Buffer
+	buf := bufio.NewWriter(&buffer)
+
+	importPaths := []string{"time"}
+	if len(enums) > 0 {
+		importPaths = append(importPaths, "database/sql/driver", "encoding/json", "fmt")
+	}
+	if opts.Stringer {
+		importPaths = append(importPaths, "fmt", "strings")
+	}
+	if opts.Clone {
+		importPaths = append(importPaths, "encoding/json")
+	}
+	if opts.UpdateHelpers {
+		importPaths = append(importPaths, "context", "database/sql", "errors")
+		if shared {
+			importPaths = append(importPaths, "database/sql/driver", "encoding/json", "reflect", "strconv", "strings", "github.com/lib/pq")
+		}
+	}
+	if opts.NextvalHelpers {
+		importPaths = append(importPaths, "context", "database/sql")
+	}
+	if opts.Registry && shared {
+		importPaths = append(importPaths, "reflect")
+	}
+	for _, model := range append(append([]Model(nil), embeds...), models...) {
+		for _, field := range model.Fields {
+			if field.Import != "" {
+				importPaths = append(importPaths, field.Import)
+			}
+			if field.JSONStruct != nil {
+				importPaths = append(importPaths, "database/sql/driver", "encoding/json", "fmt")
+			}
+		}
+		for _, rel := range model.Relations {
+			if rel.Field && rel.Import != "" {
+				importPaths = append(importPaths, rel.Import)
+			}
+		}
+	}
+	importPaths = uniqueStrings(append(importPaths, opts.Imports...))
+	headerTmpl, err := template.New("header").Parse(headerTpl)
+	if err != nil {
+		return nil, err
+	}
+	if err := headerTmpl.Execute(buf, struct {
+		BuildTag string
+		Package  string
+		Imports  []string
+	}{opts.BuildTag, opts.Package, importPaths}); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+
+	for _, enum := range enums {
+		tmpl, err := template.New("enum").Parse(enumTpl)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tmpl.Execute(buf, enum); err != nil {
+			return nil, err
+		}
+		if opts.EnumText {
+			textTmpl, err := template.New("enum_text").Parse(enumTextTpl)
+			if err != nil {
+				return nil, err
+			}
+			if err := textTmpl.Execute(buf, enum); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.Clone && shared {
+		buf.WriteString(cloneValueTpl)
+	}
+	if opts.UpdateHelpers && shared {
+		buf.WriteString(updateSharedTpl)
+	}
+	if opts.NextvalHelpers && shared {
+		buf.WriteString(sequenceSharedTpl)
+	}
+	if opts.Meta && shared {
+		buf.WriteString(metaSharedTpl)
+	}
+	if opts.Registry && shared {
+		tmpl, err := template.New("registry").Parse(registryTpl)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tmpl.Execute(buf, registry); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, embed := range embeds {
+		tmpl, err := template.New("embed").Parse(embedTpl)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tmpl.Execute(buf, withTags(embed, opts)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, model := range models {
+		report.Steps.Add(1)
+		for _, field := range model.Fields {
+			if field.Type == "" {
+				return nil, fmt.Errorf("%s.%s: type not detected for %s", model.Key(), field.Column.ColumnName, field.Column.UDTName)
+			}
+		}
+
+		tmpl, err := template.New("test").Parse(modelTpl)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tmpl.Execute(buf, withTags(model, opts)); err != nil {
+			return nil, err
+		}
+
+		for _, s := range jsonStructs(model) {
+			jsonTmpl, err := template.New("json_struct").Parse(jsonStructTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := jsonTmpl.Execute(buf, s); err != nil {
+				return nil, err
+			}
+		}
+
+		if seqs := sequenceFields(model); len(seqs) > 0 && (opts.SequenceConstants || opts.NextvalHelpers) {
+			seqTmpl, err := template.New("sequences").Parse(sequenceTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := seqTmpl.Execute(buf, seqs); err != nil {
+				return nil, err
+			}
+
+			if opts.NextvalHelpers {
+				nextvalTmpl, err := template.New("nextval").Parse(nextvalTpl)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := nextvalTmpl.Execute(buf, seqs); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if opts.DTO {
+			dtoTmpl, err := template.New("dto").Parse(dtoTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := dtoTmpl.Execute(buf, withTags(model, opts)); err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.Constructors {
+			constructorTmpl, err := template.New("constructor").Parse(constructorTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			params := constructorParams(model)
+			if err := constructorTmpl.Execute(buf, struct {
+				Name   string
+				Params []ConstructorParam
+				Embeds []ConstructorEmbed
+			}{model.Name, params, constructorEmbeds(model, params)}); err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.Clone {
+			cloneTmpl, err := template.New("clone").Parse(cloneTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := cloneTmpl.Execute(buf, struct {
+				Name       string
+				Statements []string
+			}{model.Name, cloneStatements(model)}); err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.UpdateHelpers {
+			updateTmpl, err := template.New("update").Parse(updateTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if query, ok := updateQuery(model); ok {
+				if err := updateTmpl.Execute(buf, query); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if opts.JoinHelpers {
+			joinTmpl, err := template.New("joins").Parse(joinTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := joinTmpl.Execute(buf, model); err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.Stringer {
+			stringerTmpl, err := template.New("stringer").Parse(stringerTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := stringerTmpl.Execute(buf, struct {
+				Name   string
+				Fields []StringerField
+			}{model.Name, stringerFields(model, opts)}); err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.Meta {
+			metaTmpl, err := template.New("meta").Parse(metaTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := metaTmpl.Execute(buf, modelMeta(model)); err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.CustomRegions {
+			regionTmpl, err := template.New("region").Parse(customRegionTpl)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := regionTmpl.Execute(buf, model.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buf.Flush()
+	// unlike format.Source this also drops unused imports and adds missing
+	// ones, so the import list above doesn't have to be exact
+	return imports.Process("models.go", buffer.Bytes(), nil)
+}
@@ -0,0 +1,299 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// testModels builds the models of the tables ddl creates the way generate
+// does, with enums and relations.
+func testModels(t *testing.T, ddl string) ([]Model, []Enum) {
+	t.Helper()
+	s, err := introspect.ParseDDL(ddl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enums := Enums(s.Enums, EnumNaming{})
+	generated := typemap.New()
+	AddEnums(generated, enums)
+	models := BuildModels(s.Tables, typemap.Chain{generated, introspect.Postgres.Types()})
+	NameModels(models, NamingOptions{Singular: true})
+	LinkRelations(models, s.ForeignKeys)
+	return models, enums
+}
+
+// renderTestGo renders models into a single file of package models, which
+// has to type check, and returns its source.
+func renderTestGo(t *testing.T, models []Model, enums []Enum, opts GoOptions) string {
+	t.Helper()
+	opts.Package = "models"
+	files, err := RenderGo(models, enums, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := make([]string, len(files))
+	for i, file := range files {
+		srcs[i] = string(file.Content)
+	}
+	src := strings.Join(srcs, "\n")
+	if err := typeCheck(srcs...); err != nil {
+		t.Fatalf("generated code doesn't compile: %v\n%s", err, src)
+	}
+	return src
+}
+
+// testImporter imports the standard library from source and stubs the
+// packages generated code imports from elsewhere.
+type testImporter struct {
+	std   types.Importer
+	stubs map[string]*types.Package
+}
+
+var (
+	testFset     = token.NewFileSet()
+	goTestImport = &testImporter{
+		std:   importer.ForCompiler(testFset, "source", nil),
+		stubs: make(map[string]*types.Package),
+	}
+	// importStubs declare what generated code uses of other packages.
+	importStubs = map[string]string{
+		"github.com/lib/pq": `package pq
+
+import "database/sql/driver"
+
+func Array(a interface{}) interface {
+	Value() (driver.Value, error)
+} {
+	return nil
+}
+`,
+	}
+)
+
+func (im *testImporter) Import(path string) (*types.Package, error) {
+	src, ok := importStubs[path]
+	if !ok {
+		return im.std.Import(path)
+	}
+	if pkg, ok := im.stubs[path]; ok {
+		return pkg, nil
+	}
+	pkg, err := checkFiles(path, src)
+	if err != nil {
+		return nil, err
+	}
+	im.stubs[path] = pkg
+	return pkg, nil
+}
+
+// typeCheck reports the first error type checking the files in srcs, which
+// make up one package.
+func typeCheck(srcs ...string) error {
+	_, err := checkFiles("models", srcs...)
+	return err
+}
+
+func checkFiles(path string, srcs ...string) (*types.Package, error) {
+	files := make([]*ast.File, len(srcs))
+	for i, src := range srcs {
+		f, err := parser.ParseFile(testFset, "", src, 0)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = f
+	}
+	conf := types.Config{Importer: goTestImport}
+	return conf.Check(path, testFset, files, nil)
+}
+
+// testDDL has a column of most kinds generated code deals with.
+const testDDL = `
+	CREATE TYPE mood AS ENUM ('sad', 'happy');
+	CREATE TABLE users (
+		id bigserial PRIMARY KEY,
+		email varchar(255) NOT NULL,
+		name text,
+		mood mood,
+		tags text[],
+		settings jsonb,
+		balance numeric(10,2),
+		created_at timestamp NOT NULL DEFAULT now(),
+		updated_at timestamp
+	);
+	CREATE TABLE orders (
+		id bigserial PRIMARY KEY,
+		user_id bigint NOT NULL REFERENCES users (id),
+		note text,
+		created_at timestamp NOT NULL DEFAULT now(),
+		updated_at timestamp
+	);`
+
+func TestRenderGoDTO(t *testing.T) {
+	for _, embed := range []bool{false, true} {
+		models, enums := testModels(t, testDDL)
+		src := renderTestGo(t, models, enums, GoOptions{DTO: true, JSONTags: true, EmbedTimestamps: embed})
+		wants := []string{
+			"type UserDTO struct",
+			"func (m *User) ToDTO() *UserDTO",
+			"func UserFromDTO(dto *UserDTO) *User",
+			"func OrderFromDTO(dto *OrderDTO) *Order",
+		}
+		if embed {
+			wants = append(wants, "Timestamps: Timestamps{")
+		}
+		for _, want := range wants {
+			if !strings.Contains(src, want) {
+				t.Errorf("embed timestamps %v: no %q in\n%s", embed, want, src)
+			}
+		}
+	}
+}
+
+func TestRenderGoEnums(t *testing.T) {
+	models, enums := testModels(t, testDDL)
+	for _, opts := range []GoOptions{{}, {EnumText: true}} {
+		src := renderTestGo(t, models, enums, opts)
+		wants := []string{
+			"type Mood string",
+			`MoodSad   Mood = "sad"`,
+			"func (e *Mood) Scan(src interface{}) error",
+			"func (e Mood) Value() (driver.Value, error)",
+			"*Mood ",
+		}
+		if opts.EnumText {
+			wants = append(wants, "func (e *Mood) UnmarshalText(text []byte) error")
+		}
+		for _, want := range wants {
+			if !strings.Contains(src, want) {
+				t.Errorf("enum text %v: no %q in\n%s", opts.EnumText, want, src)
+			}
+		}
+	}
+}
+
+func TestRenderGoStringer(t *testing.T) {
+	models, enums := testModels(t, testDDL)
+	src := renderTestGo(t, models, enums, GoOptions{
+		Stringer:       true,
+		StringerFields: []string{"users.name", "email"},
+		Sensitive:      []string{"email"},
+	})
+	for _, want := range []string{
+		"func (m *User) String() string",
+		`b.WriteString("User{")`,
+		`fmt.Fprintf(&b, "ID: %v", m.ID)`,
+		`b.WriteString(", Email: [REDACTED]")`,
+		`fmt.Fprintf(&b, ", Name: %v", *m.Name)`,
+		"func (m *Order) String() string",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("no %q in\n%s", want, src)
+		}
+	}
+}
+
+func TestRenderGoConstructors(t *testing.T) {
+	models, enums := testModels(t, testDDL+`
+		CREATE TABLE events (
+			id bigserial PRIMARY KEY,
+			type text NOT NULL,
+			len int NOT NULL,
+			time timestamp NOT NULL,
+			mood mood NOT NULL,
+			created_at timestamp NOT NULL,
+			updated_at timestamp NOT NULL
+		);
+		CREATE TABLE audits (
+			id bigserial PRIMARY KEY,
+			created_at timestamp NOT NULL,
+			updated_at timestamp NOT NULL
+		);`)
+	src := renderTestGo(t, models, enums, GoOptions{Constructors: true, EmbedTimestamps: true})
+	for _, want := range []string{
+		"func NewUser(email string) *User",
+		"func NewEvent(type_ string, len_ int, time_ time.Time, mood Mood, createdAt time.Time, updatedAt time.Time) *Event",
+		"Timestamps: Timestamps{",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("no %q in\n%s", want, src)
+		}
+	}
+}
+
+func TestRenderGoClone(t *testing.T) {
+	models, enums := testModels(t, testDDL+`
+		CREATE TABLE profiles (
+			id bigserial PRIMARY KEY,
+			prefs jsonb NOT NULL,
+			extra jsonb
+		);`)
+	MarkHasMany(models, []string{"orders"})
+	shape := map[string]string{"theme": "*string", "tags": "[]string", "labels": "map[string]string", "misc": "interface{}", "size": "int"}
+	ApplyJSONTypes(models, map[string]map[string]string{"profiles.prefs": shape, "profiles.extra": shape})
+	src := renderTestGo(t, models, enums, GoOptions{Clone: true})
+	for _, want := range []string{
+		"func (m *User) Clone() *User",
+		"v := cloneValue(*m.Settings)",
+		"c.Orders = make([]*Order, len(m.Orders))",
+		"c.Orders[i] = v.Clone()",
+		"c.Prefs.Tags = make([]string, len(m.Prefs.Tags))",
+		"c.Prefs.Labels[k] = v",
+		"c.Prefs.Misc = cloneValue(m.Prefs.Misc)",
+		"c.Prefs.Theme = &v",
+		"c.Extra = new(ProfileExtra)",
+		"c.Extra.Theme = &v",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("no %q in\n%s", want, src)
+		}
+	}
+}
+
+func TestRenderGoHasMany(t *testing.T) {
+	models, enums := testModels(t, testDDL+`
+		CREATE TABLE employees (
+			id bigserial PRIMARY KEY,
+			manager_id bigint REFERENCES employees (id)
+		);`)
+	MarkHasMany(models, []string{"orders", "employees.employees"})
+	src := renderTestGo(t, models, enums, GoOptions{JoinHelpers: true, DTO: true, Clone: true})
+	for _, want := range []string{
+		"Orders    []*Order     `pg:\"fk:user_id\"`",
+		"Employees []*Employee `pg:\"fk:manager_id\"`",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("no %q in\n%s", want, src)
+		}
+	}
+}
+
+func TestRenderGoCollisions(t *testing.T) {
+	models, enums := testModels(t, `
+		CREATE TYPE mood AS ENUM ('sad', 'happy');
+		CREATE TABLE people (id bigserial PRIMARY KEY);
+		CREATE TABLE moods (
+			id bigserial PRIMARY KEY,
+			person_id bigint REFERENCES people (id),
+			clone text
+		);`)
+	MarkHasMany(models, []string{"moods"})
+	src := renderTestGo(t, models, enums, GoOptions{Clone: true, JoinHelpers: true})
+	for _, want := range []string{
+		"type Mood_ struct",
+		"Clone_ ",
+		"[]*Mood_ `pg:\"fk:person_id\"`",
+		"c.Moods = make([]*Mood_, len(m.Moods))",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("no %q in\n%s", want, src)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const graphqlTpl = `{{range .Scalars}}scalar {{.}}
+{{end}}{{range .Enums}}
+enum {{.Name}} {
+{{range .Values}}  {{.}}
+{{end}}}
+{{end}}{{range .Types}}
+type {{.Name}} {
+{{range .Fields}}  {{.Name}}: {{.Type}}
+{{end}}}
+{{end}}`
+
+type GraphQLSchema struct {
+	Scalars []string
+	Enums   []GraphQLEnum
+	Types   []GraphQLType
+}
+
+type GraphQLEnum struct {
+	Name   string
+	Values []string
+}
+
+type GraphQLType struct {
+	Name   string
+	Fields []GraphQLField
+}
+
+type GraphQLField struct {
+	Name string
+	Type string
+}
+
+var (
+	graphqlScalars = map[string]string{
+		"bool":        "Boolean",
+		"int2":        "Int",
+		"int4":        "Int",
+		"int8":        "BigInt",
+		"float4":      "Float",
+		"float8":      "Float",
+		"numeric":     "String",
+		"varchar":     "String",
+		"text":        "String",
+		"bpchar":      "String",
+		"tsvector":    "String",
+		"tsquery":     "String",
+		"uuid":        "ID",
+		"timestamp":   "Time",
+		"timestamptz": "Time",
+		"date":        "Time",
+		"json":        "JSON",
+		"jsonb":       "JSON",
+	}
+
+	// graphqlCustomScalars are not built into GraphQL and have to be declared.
+	// Int is 32 bits wide, int8 is a BigInt.
+	graphqlCustomScalars = map[string]bool{
+		"BigInt": true,
+		"Time":   true,
+		"JSON":   true,
+	}
+)
+
+// graphqlEnum returns the GraphQL enum of type name with labels, named
+// like the Go one. Values are labels in upper case with words joined by
+// underscores, which GraphQL names can hold.
+func graphqlEnum(name string, labels []string) GraphQLEnum {
+	enum := GraphQLEnum{Name: toCamelCase(name)}
+	taken := make(map[string]bool, len(labels))
+	for i, label := range labels {
+		value := capsWords(label)
+		switch {
+		case value == "":
+			value = "VALUE_" + strconv.Itoa(i+1)
+		case value[0] >= '0' && value[0] <= '9', value == "TRUE", value == "FALSE", value == "NULL":
+			value = "_" + value
+		}
+		v := value
+		for n := 2; taken[v]; n++ {
+			v = value + "_" + strconv.Itoa(n)
+		}
+		taken[v] = true
+		enum.Values = append(enum.Values, v)
+	}
+	return enum
+}
+
+func graphqlType(col introspect.DBColumn, enums map[string]GraphQLEnum) (string, error) {
+	udt, isArray := elementType(col.UDTName)
+
+	t, ok := graphqlScalars[udt]
+	if enum, isEnum := enums[udt]; isEnum {
+		t, ok = enum.Name, true
+	}
+	if !ok {
+		return "", fmt.Errorf("graphql type not detected for %s", col.UDTName)
+	}
+	if isArray {
+		t = fmt.Sprintf("[%s!]", t)
+	}
+	if !col.IsNullable {
+		t += "!"
+	}
+	return t, nil
+}
+
+// RenderGraphQL renders a type per model and an enum per enum type columns
+// have. Foreign keys become a field holding the referenced type and, on the
+// referenced side, a list of referencing types.
+func RenderGraphQL(models []Model, dbEnums introspect.DBEnums) ([]byte, error) {
+	var (
+		schema  GraphQLSchema
+		scalars = make(map[string]bool)
+		enums   = make(map[string]GraphQLEnum, len(dbEnums))
+		used    = make(map[string]bool)
+	)
+	names := map[string]bool{"Boolean": true, "Int": true, "Float": true, "String": true, "ID": true}
+	for scalar := range graphqlCustomScalars {
+		names[scalar] = true
+	}
+	for _, model := range models {
+		names[model.Name] = true
+	}
+	for typeName, labels := range dbEnums {
+		enum := graphqlEnum(typeName, labels)
+		// types named like a model or scalar leave the name to them
+		if names[enum.Name] {
+			enum.Name += "Enum"
+		}
+		enums[typeName] = enum
+	}
+
+	for _, model := range models {
+		report.Steps.Add(1)
+		typ := GraphQLType{Name: model.Name}
+		for _, field := range model.Fields {
+			t, err := graphqlType(field.Column, enums)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", model.Key(), field.Column.ColumnName, err)
+			}
+			scalar := strings.Trim(t, "[]!")
+			if graphqlCustomScalars[scalar] {
+				scalars[scalar] = true
+			}
+			if udt, _ := elementType(field.Column.UDTName); enums[udt].Name != "" {
+				used[udt] = true
+			}
+			typ.Fields = append(typ.Fields, GraphQLField{
+				Name: toLowerCamelCase(field.Column.ColumnName),
+				Type: t,
+			})
+		}
+		for _, rel := range model.Relations {
+			t := rel.Model
+			switch {
+			case rel.Many:
+				t = fmt.Sprintf("[%s!]!", t)
+			case !rel.Nullable:
+				t += "!"
+			}
+			typ.Fields = append(typ.Fields, GraphQLField{
+				Name: toLowerCamelCase(rel.Name),
+				Type: t,
+			})
+		}
+		schema.Types = append(schema.Types, typ)
+	}
+
+	for scalar := range scalars {
+		schema.Scalars = append(schema.Scalars, scalar)
+	}
+	sort.Strings(schema.Scalars)
+	for typeName := range used {
+		schema.Enums = append(schema.Enums, enums[typeName])
+	}
+	sort.Slice(schema.Enums, func(i, j int) bool {
+		return schema.Enums[i].Name < schema.Enums[j].Name
+	})
+
+	tmpl, err := template.New("graphql").Parse(graphqlTpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, schema); err != nil {
+		return nil, err
+	}
+	return bytes.TrimLeft(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,49 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+func TestRenderGraphQL(t *testing.T) {
+	s, err := introspect.ParseDDL(`
+		CREATE TYPE status AS ENUM ('in-progress', 'in_progress', '2xx', '+', 'null');
+		CREATE TYPE unused AS ENUM ('x');
+		CREATE TABLE jobs (
+			id bigint PRIMARY KEY,
+			status status NOT NULL,
+			history status[],
+			price numeric(10,2) NOT NULL
+		);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := BuildModels(s.Tables, typemap.Fallback("string"))
+	NameModels(models, NamingOptions{Singular: true})
+	content, err := RenderGraphQL(models, s.Enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `scalar BigInt
+
+enum Status {
+  IN_PROGRESS
+  IN_PROGRESS_2
+  _2XX
+  VALUE_4
+  _NULL
+}
+
+type Job {
+  id: BigInt!
+  status: Status!
+  history: [Status!]
+  price: String!
+}
+`
+	if got := string(content); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
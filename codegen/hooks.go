@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Hook customizes a run: Models may change the models before they are
+// rendered, e.g. to add fields every model should have, Files gets the paths
+// of the written files, e.g. to run another formatter. Either may be nil.
+type Hook struct {
+	Name   string
+	Models func(models []Model) ([]Model, error)
+	Files  func(paths []string) error
+}
+
+var registeredHooks []Hook
+
+// RegisterHook adds h to the hooks of every run, after the ones registered
+// before it. It is meant to be called from init functions.
+func RegisterHook(h Hook) {
+	registeredHooks = append(registeredHooks, h)
+}
+
+// Hooks returns the registered hooks.
+func Hooks() []Hook {
+	return append([]Hook(nil), registeredHooks...)
+}
+
+// ModelsCommand returns a hook running the shell command with the models as
+// JSON on stdin, the command prints them back, changed as it likes.
+func ModelsCommand(command string) Hook {
+	return Hook{
+		Name: command,
+		Models: func(models []Model) ([]Model, error) {
+			in, err := json.Marshal(models)
+			if err != nil {
+				return nil, err
+			}
+			var out bytes.Buffer
+			cmd := exec.Command("sh", "-c", command)
+			cmd.Stdin = bytes.NewReader(in)
+			cmd.Stdout = &out
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return nil, err
+			}
+			var changed []Model
+			if err := json.Unmarshal(out.Bytes(), &changed); err != nil {
+				return nil, fmt.Errorf("reading its output: %w", err)
+			}
+			return changed, nil
+		},
+	}
+}
+
+// FilesCommand returns a hook running the shell command with the paths of
+// the written files as arguments, e.g. "gofumpt -w".
+func FilesCommand(command string) Hook {
+	return Hook{
+		Name: command,
+		Files: func(paths []string) error {
+			cmd := exec.Command("sh", append([]string{"-c", command + ` "$@"`, "sh"}, paths...)...)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			return cmd.Run()
+		},
+	}
+}
+
+// RunModelHooks passes models through the Models function of every hook in
+// order.
+func RunModelHooks(hooks []Hook, models []Model) ([]Model, error) {
+	for _, h := range hooks {
+		if h.Models == nil {
+			continue
+		}
+		var err error
+		if models, err = h.Models(models); err != nil {
+			return nil, fmt.Errorf("hook %s: %w", h.Name, err)
+		}
+	}
+	return models, nil
+}
+
+// RunFileHooks calls the Files function of every hook in order.
+func RunFileHooks(hooks []Hook, paths []string) error {
+	for _, h := range hooks {
+		if h.Files == nil {
+			continue
+		}
+		if err := h.Files(paths); err != nil {
+			return fmt.Errorf("hook %s: %w", h.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package codegen
+
+import (
+	"strings"
+)
+
+const joinTpl = `{{range .Joins}}
+// {{.Method}} joins {{.Target}} by {{.Columns}}, a clause for go-pg's Join or
+// gorm's Joins. The joined table is aliased {{.Alias}}.
+func ({{$.Name}}) {{.Method}}() string {
+	return {{.Literal}}
+}
+{{end}}`
+
+// Join is a JOIN clause following a relation.
+type Join struct {
+	Method  string
+	Target  string
+	Alias   string
+	Columns string
+	Clause  string
+}
+
+// Literal is the clause as a Go string literal.
+func (j Join) Literal() string {
+	return stringLiteral(j.Clause)
+}
+
+// Joins returns a join per relation of m. Belongs-to relations on not null
+// columns are inner joins, others left joins, so rows without a match are
+// kept.
+func (m Model) Joins() []Join {
+	quote := quoteIdent
+	var joins []Join
+	for _, rel := range m.Relations {
+		alias := rel.Name
+		if alias == m.TableName {
+			alias += "_"
+		}
+		target := quote(rel.TableName)
+		if rel.Schema != "" {
+			target = quote(rel.Schema) + "." + target
+		}
+
+		var conds []string
+		for i, col := range rel.Columns {
+			// Columns are always the referencing ones, RefColumns the
+			// referenced
+			local, remote := col, rel.RefColumns[i]
+			if rel.Many {
+				local, remote = rel.RefColumns[i], col
+			}
+			conds = append(conds, quote(alias)+"."+quote(remote)+" = "+quote(m.TableName)+"."+quote(local))
+		}
+		kind := "LEFT JOIN"
+		if !rel.Many && !rel.Nullable {
+			kind = "JOIN"
+		}
+		joins = append(joins, Join{
+			Method:  "With" + toCamelCase(rel.Name),
+			Target:  rel.Model,
+			Alias:   alias,
+			Columns: strings.Join(rel.Columns, ", "),
+			Clause:  kind + " " + target + " AS " + quote(alias) + " ON " + strings.Join(conds, " AND "),
+		})
+	}
+	return joins
+}
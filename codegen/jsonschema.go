@@ -0,0 +1,153 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+type JSONSchema struct {
+	Schema               string               `json:"$schema"`
+	ID                   string               `json:"$id"`
+	Title                string               `json:"title"`
+	Type                 string               `json:"type"`
+	Properties           JSONSchemaProperties `json:"properties"`
+	Required             []string             `json:"required,omitempty"`
+	AdditionalProperties bool                 `json:"additionalProperties"`
+}
+
+type JSONSchemaProperty struct {
+	Name      string              `json:"-"`
+	Type      interface{}         `json:"type,omitempty"`
+	Format    string              `json:"format,omitempty"`
+	MaxLength *int                `json:"maxLength,omitempty"`
+	Enum      []interface{}       `json:"enum,omitempty"`
+	Items     *JSONSchemaProperty `json:"items,omitempty"`
+}
+
+// JSONSchemaProperties keeps properties in column order when marshalled.
+type JSONSchemaProperties []JSONSchemaProperty
+
+func (props JSONSchemaProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range props {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(prop)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var jsonSchemaTypes = map[string][2]string{
+	"bool":        {"boolean", ""},
+	"int2":        {"integer", ""},
+	"int4":        {"integer", ""},
+	"int8":        {"integer", ""},
+	"float4":      {"number", ""},
+	"float8":      {"number", ""},
+	"numeric":     {"number", ""},
+	"varchar":     {"string", ""},
+	"text":        {"string", ""},
+	"bpchar":      {"string", ""},
+	"tsvector":    {"string", ""},
+	"tsquery":     {"string", ""},
+	"bytea":       {"string", ""},
+	"uuid":        {"string", "uuid"},
+	"timestamp":   {"string", "date-time"},
+	"timestamptz": {"string", "date-time"},
+	"date":        {"string", "date"},
+	"json":        {"", ""},
+	"jsonb":       {"", ""},
+}
+
+func jsonSchemaProperty(col introspect.DBColumn, enums introspect.DBEnums) (JSONSchemaProperty, error) {
+	udt, isArray := elementType(col.UDTName)
+
+	var prop JSONSchemaProperty
+	if labels, ok := enums[udt]; ok {
+		for _, label := range labels {
+			prop.Enum = append(prop.Enum, label)
+		}
+	} else if t, ok := jsonSchemaTypes[udt]; ok {
+		if t[0] != "" {
+			prop.Type = t[0]
+		}
+		prop.Format = t[1]
+		if t[0] == "string" && !isArray {
+			prop.MaxLength = col.CharacterMaximumLength
+		}
+	} else {
+		return prop, fmt.Errorf("json schema type not detected for %s", col.UDTName)
+	}
+
+	if isArray {
+		items := prop
+		prop = JSONSchemaProperty{Type: "array", Items: &items}
+	}
+
+	if col.IsNullable {
+		switch {
+		case prop.Enum != nil:
+			prop.Enum = append(prop.Enum, nil)
+		case prop.Type != nil:
+			prop.Type = []string{prop.Type.(string), "null"}
+		}
+	}
+	prop.Name = col.ColumnName
+	return prop, nil
+}
+
+// RenderJSONSchema renders a schema document per model. Columns that are not
+// null and have no default are required, since the database can't fill them.
+func RenderJSONSchema(models []Model, enums introspect.DBEnums) ([]OutputFile, error) {
+	files := make([]OutputFile, 0, len(models))
+	names := tableFileNames(models, ".schema.json")
+	for i, model := range models {
+		report.Steps.Add(1)
+		fileName := names[i]
+		schema := JSONSchema{
+			Schema: jsonSchemaDraft,
+			ID:     fileName,
+			Title:  model.Name,
+			Type:   "object",
+		}
+		for _, field := range model.Fields {
+			prop, err := jsonSchemaProperty(field.Column, enums)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", model.Key(), field.Column.ColumnName, err)
+			}
+			schema.Properties = append(schema.Properties, prop)
+			if !field.Column.IsNullable && field.Column.ColumnDefault == nil {
+				schema.Required = append(schema.Required, field.Column.ColumnName)
+			}
+		}
+
+		content, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, OutputFile{
+			Name:    fileName,
+			Content: append(content, '\n'),
+		})
+	}
+	return files, nil
+}
@@ -0,0 +1,33 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+func TestRenderJSONSchemaFileNames(t *testing.T) {
+	s, err := introspect.ParseDDL(`
+		CREATE TABLE users (id integer PRIMARY KEY);
+		CREATE TABLE billing.users (id integer PRIMARY KEY);
+		CREATE TABLE billing.invoices (id integer PRIMARY KEY);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := BuildModels(s.Tables, typemap.Fallback("string"))
+	NameModels(models, NamingOptions{Singular: true})
+	files, err := RenderJSONSchema(models, s.Enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool, len(files))
+	for _, file := range files {
+		got[file.Name] = true
+	}
+	for _, want := range []string{"users.schema.json", "billing.users.schema.json", "invoices.schema.json"} {
+		if !got[want] {
+			t.Errorf("no %s in %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package codegen
+
+import (
+	"sort"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+const jsonStructTpl = `
+// {{.Name}} is the value of {{.Column}}, stored as JSON.
+type {{.Name}} struct {
+{{range .Fields}}	{{.Name}} {{.Type}} ` + "`" + `json:"{{.Key}}"` + "`" + `
+{{end}}}
+
+func (v *{{.Name}}) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	default:
+		return fmt.Errorf("{{.Name}}: cannot scan %T", src)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (v {{.Name}}) Value() (driver.Value, error) {
+	return json.Marshal(v)
+}
+`
+
+// JSONStruct is the struct a json or jsonb column is decoded into.
+type JSONStruct struct {
+	Name string
+	// Column is the "table.column" holding it.
+	Column string
+	Fields []JSONField
+}
+
+type JSONField struct {
+	Name string
+	Type string
+	Key  string
+}
+
+// ApplyJSONTypes types the json and jsonb columns shapes are given for with
+// a struct of their own named after model and field, so it has to run after
+// NameModels. Keys are "table.column" or "schema.table.column", shapes map
+// JSON keys to Go types and become fields in key order.
+func ApplyJSONTypes(models []Model, shapes map[string]map[string]string) {
+	for i, model := range models {
+		for j, field := range model.Fields {
+			shape, ok := shapes[model.TableName+"."+field.Column.ColumnName]
+			if !ok {
+				shape, ok = shapes[model.Key()+"."+field.Column.ColumnName]
+			}
+			if !ok {
+				continue
+			}
+			if udt := field.Column.UDTName; udt != "json" && udt != "jsonb" {
+				report.Debug.Printf("column %s.%s: %s is no json, not typing it", model.Key(), field.Column.ColumnName, udt)
+				continue
+			}
+			s := &JSONStruct{
+				Name:   model.Name + field.Name,
+				Column: model.TableName + "." + field.Column.ColumnName,
+			}
+			for key, t := range shape {
+				s.Fields = append(s.Fields, JSONField{Name: toCamelCase(key), Type: t, Key: key})
+			}
+			sort.Slice(s.Fields, func(a, b int) bool {
+				return s.Fields[a].Key < s.Fields[b].Key
+			})
+			f := &models[i].Fields[j]
+			f.JSONStruct = s
+			f.Type = s.Name
+			if f.Nullable {
+				f.Type = "*" + s.Name
+			}
+			f.Import = ""
+		}
+	}
+}
+
+// jsonStructs returns the JSON structs of model's fields.
+func jsonStructs(model Model) []JSONStruct {
+	var structs []JSONStruct
+	for _, field := range model.Fields {
+		if field.JSONStruct != nil {
+			structs = append(structs, *field.JSONStruct)
+		}
+	}
+	return structs
+}
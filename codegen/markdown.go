@@ -0,0 +1,80 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const markdownTpl = `# Data dictionary
+{{range .}}
+- [{{.TableName}}](#{{.TableName}}){{end}}
+{{range .}}
+## {{.TableName}}
+{{if .Comment}}
+{{cell .Comment}}
+{{end}}
+| Column | Type | Nullable | Default | Comment |
+| --- | --- | --- | --- | --- |
+{{range .Fields}}| {{.Column.ColumnName}} | {{columnType .Column}} | {{if .Nullable}}yes{{else}}no{{end}} | {{with .Column.ColumnDefault}}` + "`{{cell .}}`" + `{{end}} | {{with .Column.Comment}}{{cell .}}{{end}} |
+{{end}}{{with references .Relations}}
+References:
+{{range .}}
+- {{join .Columns ", "}} → [{{.TableName}}](#{{.TableName}}) ({{join .RefColumns ", "}}){{end}}
+{{end}}{{end}}`
+
+// columnType formats the column type including its length or precision and
+// scale, e.g. varchar(255) or numeric(12,2).
+func columnType(col introspect.DBColumn) string {
+	t := sqlType(col)
+	var mod string
+	switch {
+	case col.CharacterMaximumLength != nil:
+		mod = fmt.Sprintf("(%d)", *col.CharacterMaximumLength)
+	case col.NumericPrecision != nil && col.NumericScale != nil && strings.TrimPrefix(col.UDTName, "_") == "numeric":
+		mod = fmt.Sprintf("(%d,%d)", *col.NumericPrecision, *col.NumericScale)
+	}
+	if mod != "" {
+		t = strings.TrimSuffix(t, "[]") + mod
+		if strings.HasPrefix(col.UDTName, "_") {
+			t += "[]"
+		}
+	}
+	return t
+}
+
+// markdownCell escapes text so it fits into a single table cell.
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(text)
+}
+
+func RenderMarkdown(models []Model) ([]byte, error) {
+	tmpl, err := template.New("markdown").Funcs(template.FuncMap{
+		"cell":       markdownCell,
+		"columnType": columnType,
+		"join":       strings.Join,
+		"references": func(relations []Relation) []Relation {
+			var refs []Relation
+			for _, rel := range relations {
+				if !rel.Many {
+					refs = append(refs, rel)
+				}
+			}
+			return refs
+		},
+	}).Parse(markdownTpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, models); err != nil {
+		return nil, err
+	}
+	report.Steps.Add(len(models))
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package codegen
+
+const (
+	metaSharedTpl = `
+// ModelMeta describes the table of a model.
+type ModelMeta struct {
+	Table      string
+	PrimaryKey []string
+	Columns    []ColumnMeta
+}
+
+// ColumnMeta describes a column and the field holding it.
+type ColumnMeta struct {
+	Name       string
+	Field      string
+	SQLType    string
+	GoType     string
+	Nullable   bool
+	PrimaryKey bool
+}
+`
+
+	metaTpl = `
+var {{.Var}} = ModelMeta{
+	Table: {{printf "%q" .Table}},
+	PrimaryKey: []string{ {{range $i, $c := .PrimaryKey}}{{if $i}}, {{end}}{{printf "%q" $c}}{{end}} },
+	Columns: []ColumnMeta{
+{{range .Columns}}		{Name: {{printf "%q" .Name}}, Field: {{printf "%q" .Field}}, SQLType: {{printf "%q" .SQLType}}, GoType: {{printf "%q" .GoType}}, Nullable: {{.Nullable}}, PrimaryKey: {{.PrimaryKey}}},
+{{end}}	},
+}
+
+// Meta describes the table of {{.Name}}, its columns and their fields.
+func ({{.Name}}) Meta() ModelMeta {
+	return {{.Var}}
+}
+`
+)
+
+// ModelMeta is the generated ModelMeta of a model.
+type ModelMeta struct {
+	Name       string
+	Var        string
+	Table      string
+	PrimaryKey []string
+	Columns    []ColumnMeta
+}
+
+// ColumnMeta is the generated ColumnMeta of a field.
+type ColumnMeta struct {
+	Name       string
+	Field      string
+	SQLType    string
+	GoType     string
+	Nullable   bool
+	PrimaryKey bool
+}
+
+// modelMeta returns the metadata of model.
+func modelMeta(model Model) ModelMeta {
+	meta := ModelMeta{
+		Name:  model.Name,
+		Var:   toLowerCamelCase(model.Name) + "Meta",
+		Table: model.SQLName(),
+	}
+	for _, field := range model.Fields {
+		col := field.Column
+		if col.IsPrimaryKey {
+			meta.PrimaryKey = append(meta.PrimaryKey, col.ColumnName)
+		}
+		meta.Columns = append(meta.Columns, ColumnMeta{
+			Name:       col.ColumnName,
+			Field:      field.Name,
+			SQLType:    columnType(col),
+			GoType:     field.Type,
+			Nullable:   field.Nullable,
+			PrimaryKey: col.IsPrimaryKey,
+		})
+	}
+	return meta
+}
@@ -0,0 +1,583 @@
+// Package codegen turns introspected tables into models and renders them in
+// the supported output formats.
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// dbOnlyTypes lists column types that make no sense outside of the database
+// and are therefore left out of generated DTOs and Update helpers. They are
+// read as their text form and tagged with their type.
+var dbOnlyTypes = map[string]bool{
+	"tsvector": true,
+	"tsquery":  true,
+}
+
+// elementType returns the element type of array types, including pgvector's
+// vectors of float4, and whether udt is one.
+func elementType(udt string) (string, bool) {
+	switch {
+	case udt == "vector":
+		return "float4", true
+	case strings.HasPrefix(udt, "_"):
+		return udt[1:], true
+	}
+	return udt, false
+}
+
+// BuildModels turns every table into a model, with field types resolved by
+// typer.
+func BuildModels(tables introspect.DBTables, typer typemap.Typer) []Model {
+	models := make([]Model, 0, len(tables))
+
+	for _, columns := range tables {
+		modelFields := make([]Field, 0, len(columns))
+
+		sort.Slice(columns, func(i, j int) bool {
+			return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+		})
+
+		for _, col := range columns {
+			modelFields = append(modelFields, newField(col, typer))
+		}
+
+		models = append(models, Model{
+			Name:      toCamelCase(columns[0].TableName),
+			Schema:    columns[0].TableSchema,
+			TableName: columns[0].TableName,
+			Fields:    modelFields,
+		})
+	}
+
+	// tables come from a map, sort them to keep regenerated files stable
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Key() < models[j].Key()
+	})
+
+	return models
+}
+
+// ApplyNullTime gives nullable time columns goType instead of *time.Time,
+// unless it's empty.
+func ApplyNullTime(models []Model, goType string) {
+	if goType == "" {
+		return
+	}
+	for i, model := range models {
+		for j, field := range model.Fields {
+			if field.Nullable && field.Type == "*time.Time" {
+				models[i].Fields[j].Type = goType
+				models[i].Fields[j].Import = ""
+			}
+		}
+	}
+}
+
+// ApplyColumnTypes overrides the Go type of single columns. Keys are
+// "table.column" or "schema.table.column", nullable columns become pointers.
+func ApplyColumnTypes(models []Model, types map[string]string) {
+	for i, model := range models {
+		for j, field := range model.Fields {
+			t, ok := types[model.TableName+"."+field.Column.ColumnName]
+			if !ok {
+				t, ok = types[model.Key()+"."+field.Column.ColumnName]
+			}
+			if !ok {
+				continue
+			}
+			if field.Nullable {
+				t = "*" + t
+			}
+			models[i].Fields[j].Type = t
+			models[i].Fields[j].Import = ""
+		}
+	}
+}
+
+// RenameFields overrides the names of single fields. Keys are "table.column"
+// or "schema.table.column".
+func RenameFields(models []Model, names map[string]string) {
+	for i, model := range models {
+		for j, field := range model.Fields {
+			name, ok := names[model.TableName+"."+field.Column.ColumnName]
+			if !ok {
+				name, ok = names[model.Key()+"."+field.Column.ColumnName]
+			}
+			if ok {
+				models[i].Fields[j].Name = name
+			}
+		}
+	}
+}
+
+// MarkSoftDelete marks the first timestamp column of each model in columns,
+// "column" or "table.column", as its soft delete column and tags it so that
+// go-pg sets it instead of deleting the row.
+func MarkSoftDelete(models []Model, columns []string) {
+	for i, model := range models {
+		for j, field := range model.Fields {
+			if !matchColumn(columns, model.TableName, field.Column.ColumnName) {
+				continue
+			}
+			if !timestampTypes[field.Column.UDTName] {
+				report.Debug.Printf("column %s.%s: %s is no timestamp, not using it for soft deletes", model.Key(), field.Column.ColumnName, field.Column.UDTName)
+				continue
+			}
+			f := &models[i].Fields[j]
+			f.SoftDelete = true
+			f.Tag = strings.TrimSuffix(f.Tag, `"`) + `,soft_delete"`
+			break
+		}
+	}
+}
+
+// SoftDelete returns the soft delete field of the model, if it has one.
+func (m Model) SoftDelete() *Field {
+	for i := range m.Fields {
+		if m.Fields[i].SoftDelete {
+			return &m.Fields[i]
+		}
+	}
+	return nil
+}
+
+// SortFields orders the fields of models: "ordinal" keeps the table's column
+// order, "alphabetical" sorts by name, "pk-first" moves the primary key to the
+// front and "grouped" orders keys (primary, then foreign), data and then
+// timestamps. Run it after LinkRelations, grouped looks at relations.
+func SortFields(models []Model, order string) {
+	for _, model := range models {
+		var rank func(Field) int
+		switch order {
+		case "alphabetical":
+			sort.SliceStable(model.Fields, func(i, j int) bool {
+				return model.Fields[i].Name < model.Fields[j].Name
+			})
+			continue
+		case "pk-first":
+			rank = func(f Field) int {
+				if f.Column.IsPrimaryKey {
+					return 0
+				}
+				return 1
+			}
+		case "grouped":
+			foreign := make(map[string]bool)
+			for _, rel := range model.Relations {
+				if !rel.Many {
+					for _, column := range rel.Columns {
+						foreign[column] = true
+					}
+				}
+			}
+			rank = func(f Field) int {
+				switch {
+				case f.Column.IsPrimaryKey:
+					return 0
+				case foreign[f.Column.ColumnName]:
+					return 1
+				case timestampTypes[f.Column.UDTName]:
+					return 3
+				}
+				return 2
+			}
+		default:
+			continue
+		}
+		sort.SliceStable(model.Fields, func(i, j int) bool {
+			return rank(model.Fields[i]) < rank(model.Fields[j])
+		})
+	}
+}
+
+// timestampTypes are the column types "grouped" field order puts last.
+var timestampTypes = map[string]bool{
+	"timestamp":   true,
+	"timestamptz": true,
+	"date":        true,
+	"datetime":    true,
+	"datetime2":   true,
+}
+
+type Model struct {
+	Name      string
+	Schema    string
+	TableName string
+	Fields    []Field
+	Relations []Relation
+	Comment   string
+	// Package is the directory below the output of the package the model
+	// is generated into, empty for the output package.
+	Package string
+	// Triggers are the triggers of the table, listed on the struct.
+	Triggers []introspect.DBTrigger
+	// RowSecurity is set if the table has row level security enabled.
+	RowSecurity *introspect.DBTableSecurity
+	// Embeds are the shared structs the model embeds.
+	Embeds []string
+}
+
+// Key identifies the model's table across schemas.
+func (m Model) Key() string {
+	return m.Schema + "." + m.TableName
+}
+
+// SQLName is the table name as go-pg expects it, qualified outside of public.
+func (m Model) SQLName() string {
+	if m.Schema == "" || m.Schema == "public" {
+		return m.TableName
+	}
+	return m.Key()
+}
+
+// TagSQLName is SQLName for go-pg's tableName tag, which takes SQL: names
+// Postgres would otherwise fold to lower case or not take at all are quoted.
+func (m Model) TagSQLName() string {
+	quote := func(name string) string {
+		if plainTable.MatchString(name) {
+			return name
+		}
+		return quoteIdent(name)
+	}
+	if m.Schema == "" || m.Schema == "public" {
+		return tagEscape(quote(m.TableName))
+	}
+	return tagEscape(quote(m.Schema) + "." + quote(m.TableName))
+}
+
+// Relation is a foreign-key link between two models. Belongs-to relations live
+// on the referencing model, has-many (Many) on the referenced one.
+type Relation struct {
+	Name       string
+	Model      string
+	Schema     string
+	TableName  string
+	Columns    []string
+	RefColumns []string
+	Many       bool
+	Nullable   bool
+	// Field adds a has-many relation to the struct as FieldName, ForeignKey
+	// and References name the fields of Columns and RefColumns.
+	Field      bool
+	FieldName  string
+	ForeignKey string
+	References string
+	Tag        string
+	// Import is the package of Model if it's another one.
+	Import string
+}
+
+// LinkRelations attaches a belongs-to relation to the referencing model and a
+// has-many relation to the referenced model for every foreign key.
+func LinkRelations(models []Model, fks []introspect.DBForeignKey) {
+	byTable := make(map[string]int, len(models))
+	for i, m := range models {
+		byTable[m.Key()] = i
+	}
+
+	type constraint struct {
+		schema, table, name string
+	}
+	var (
+		order   []constraint
+		grouped = make(map[constraint][]introspect.DBForeignKey)
+	)
+	for _, fk := range fks {
+		key := constraint{fk.Schema, fk.TableName, fk.ConstraintName}
+		if _, ok := grouped[key]; !ok {
+			order = append(order, key)
+		}
+		grouped[key] = append(grouped[key], fk)
+	}
+
+	for _, key := range order {
+		group := grouped[key]
+		from, ok := byTable[key.schema+"."+key.table]
+		if !ok {
+			report.Debug.Printf("skipping foreign key %s: table %s.%s is not generated", key.name, key.schema, key.table)
+			continue
+		}
+		to, ok := byTable[group[0].RefSchema+"."+group[0].RefTableName]
+		if !ok {
+			report.Debug.Printf("skipping foreign key %s: table %s.%s is not generated", key.name, group[0].RefSchema, group[0].RefTableName)
+			continue
+		}
+
+		var (
+			columns    []string
+			refColumns []string
+			nullable   bool
+		)
+		for _, fk := range group {
+			columns = append(columns, fk.ColumnName)
+			refColumns = append(refColumns, fk.RefColumnName)
+			for _, f := range models[from].Fields {
+				if f.Column.ColumnName == fk.ColumnName && f.Nullable {
+					nullable = true
+				}
+			}
+		}
+
+		// belongs-to relations are named in singular, has-many ones in plural
+		name := singularize(group[0].RefTableName)
+		if len(columns) == 1 && strings.HasSuffix(columns[0], "_id") {
+			name = strings.TrimSuffix(columns[0], "_id")
+		}
+		models[from].Relations = append(models[from].Relations, Relation{
+			Name:       uniqueRelationName(models[from], name, columns),
+			Model:      models[to].Name,
+			Schema:     models[to].Schema,
+			TableName:  models[to].TableName,
+			Columns:    columns,
+			RefColumns: refColumns,
+			Nullable:   nullable,
+		})
+		models[to].Relations = append(models[to].Relations, Relation{
+			Name:       uniqueRelationName(models[to], pluralize(key.table), columns),
+			Model:      models[from].Name,
+			Schema:     models[from].Schema,
+			TableName:  models[from].TableName,
+			Columns:    columns,
+			RefColumns: refColumns,
+			Many:       true,
+		})
+	}
+}
+
+// RenameRelations overrides the names LinkRelations gave relations. Keys are
+// "table.relation" or "schema.table.relation" with the generated name.
+func RenameRelations(models []Model, names map[string]string) {
+	for i, model := range models {
+		for j, rel := range model.Relations {
+			name, ok := names[model.TableName+"."+rel.Name]
+			if !ok {
+				name, ok = names[model.Key()+"."+rel.Name]
+			}
+			if ok {
+				models[i].Relations[j].Name = name
+			}
+		}
+	}
+}
+
+// uniqueRelationName falls back to "<name>_by_<columns>" when the model already
+// has a column or relation called name, e.g. for created_by/updated_by pairs.
+func uniqueRelationName(model Model, name string, columns []string) string {
+	taken := func(n string) bool {
+		for _, f := range model.Fields {
+			if f.Column.ColumnName == n {
+				return true
+			}
+		}
+		for _, r := range model.Relations {
+			if r.Name == n {
+				return true
+			}
+		}
+		return false
+	}
+	if !taken(name) {
+		return name
+	}
+	return name + "_by_" + strings.Join(columns, "_")
+}
+
+// MarkHasMany gives models a slice field for their has-many relations in
+// relations, "relation" or "table.relation" with the relation's name. Run it
+// after RenameRelations and RenameFields.
+func MarkHasMany(models []Model, relations []string) {
+	byTable := make(map[string]Model, len(models))
+	for _, m := range models {
+		byTable[m.Key()] = m
+	}
+	fieldName := func(m Model, column string) string {
+		for _, f := range m.Fields {
+			if f.Column.ColumnName == column {
+				return f.Name
+			}
+		}
+		return ""
+	}
+	for i, model := range models {
+		for j, rel := range model.Relations {
+			if !rel.Many || !matchColumn(relations, model.TableName, rel.Name) {
+				continue
+			}
+			if len(rel.Columns) != 1 {
+				report.Debug.Printf("relation %s.%s: foreign keys of several columns get no field", model.Key(), rel.Name)
+				continue
+			}
+			name := toCamelCase(rel.Name)
+			if hasField(models[i], name) {
+				report.Debug.Printf("relation %s.%s: field %s is taken, rename the relation with relation_names", model.Key(), rel.Name, name)
+				continue
+			}
+			r := &models[i].Relations[j]
+			r.Field = true
+			r.FieldName = name
+			r.ForeignKey = fieldName(byTable[rel.Schema+"."+rel.TableName], rel.Columns[0])
+			r.References = fieldName(model, rel.RefColumns[0])
+			r.Tag = fmt.Sprintf(`pg:"fk:%s"`, tagEscape(rel.Columns[0]))
+		}
+	}
+}
+
+func hasField(m Model, name string) bool {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	for _, r := range m.Relations {
+		if r.Field && r.FieldName == name {
+			return true
+		}
+	}
+	return false
+}
+
+type Field struct {
+	Name     string
+	Type     string
+	Tag      string
+	JSONName string
+	Nullable bool
+	DBOnly   bool
+	Column   introspect.DBColumn
+	// Import is the import path Type needs, if the Typer knows one.
+	Import string
+	// Embed is the shared struct the field is promoted from, if any.
+	Embed string
+	// SoftDelete marks the column rows are deleted by setting.
+	SoftDelete bool
+	// Version marks the column optimistic locking checks and bumps.
+	Version bool
+	// Note is a comment rendered above the field.
+	Note string
+	// Validate are the rules of the field's validate tag.
+	Validate string
+	// Sequence is the sequence filling the column, as nextval takes it.
+	Sequence string
+	// JSONStruct is the struct declared for the column's JSON, if any.
+	JSONStruct *JSONStruct
+	// Sensitive marks a field String() redacts and DTOs leave out, whatever
+	// go.sensitive lists.
+	Sensitive bool
+}
+
+// InDTO reports whether f is a field of DTOs: fields kept in the database,
+// out of JSON or sensitive aren't.
+func (f Field) InDTO() bool {
+	return !f.DBOnly && f.JSONName != "-" && !f.Sensitive
+}
+
+func newField(col introspect.DBColumn, typer typemap.Typer) Field {
+	var (
+		tag       string
+		fieldType string
+		f         Field
+	)
+
+	// an unknown type is reported by RenderGo, other formats map
+	// column types on their own
+	t, err := typer.GetType(col.UDTName)
+	if col.IsNullable {
+		tag = fmt.Sprintf(`sql:"%s"`, tagColumn(col.ColumnName))
+		if err == nil {
+			fieldType = fmt.Sprintf("*%s", t)
+		}
+	} else {
+		tag = fmt.Sprintf(`sql:"%s,notnull"`, tagColumn(col.ColumnName))
+		if err == nil {
+			fieldType = t
+		}
+	}
+	if n := col.CharacterMaximumLength; n != nil {
+		switch col.UDTName {
+		case "varchar":
+			tag = strings.TrimSuffix(tag, `"`) + fmt.Sprintf(`,type:varchar(%d)"`, *n)
+			f.Validate = fmt.Sprintf("max=%d", *n)
+		case "bpchar":
+			// blank-padded to a fixed length, which go-pg can't tell from
+			// string
+			tag = strings.TrimSuffix(tag, `"`) + fmt.Sprintf(`,type:char(%d)"`, *n)
+			f.Validate = fmt.Sprintf("max=%d", *n)
+		}
+		if f.Validate != "" && col.IsNullable {
+			f.Validate = "omitempty," + f.Validate
+		}
+	}
+	// exact numerics, so that reverse and migrations keep the definition
+	if p, s := col.NumericPrecision, col.NumericScale; p != nil && s != nil && (col.UDTName == "numeric" || col.UDTName == "decimal") {
+		tag = strings.TrimSuffix(tag, `"`) + fmt.Sprintf(`,type:numeric(%d,%d)"`, *p, *s)
+	}
+	if col.UDTName == "vector" && col.CharacterMaximumLength != nil {
+		tag = strings.TrimSuffix(tag, `"`) + fmt.Sprintf(`,type:vector(%d)"`, *col.CharacterMaximumLength)
+	}
+	if dbOnlyTypes[col.UDTName] {
+		tag = strings.TrimSuffix(tag, `"`) + fmt.Sprintf(`,type:%s"`, col.UDTName)
+	}
+	f.Tag = tag
+	f.Type = fieldType
+	if err == nil {
+		f.Import = typemap.Import(typer, t)
+	}
+	f.Name = toCamelCase(col.ColumnName)
+	if f.Name == "" {
+		f.Name = fmt.Sprintf("Column%d", col.OrdinalPosition)
+	}
+	f.JSONName = jsonName(col.ColumnName)
+	f.Nullable = col.IsNullable
+	f.DBOnly = dbOnlyTypes[col.UDTName]
+	f.Sequence = sequenceName(col)
+	f.Column = col
+
+	return f
+}
+
+var (
+	// plainColumn matches the column names written into tags as they are,
+	// go-pg quotes them
+	plainColumn = regexp.MustCompile(`^[\p{L}\p{N}_$]+$`)
+	// plainTable matches the table names that need no quotes in SQL
+	plainTable = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+)
+
+// tagColumn writes column into a sql tag. Other than plain names are quoted
+// as SQL identifiers, which go-pg uses as they are.
+func tagColumn(column string) string {
+	if plainColumn.MatchString(column) {
+		return column
+	}
+	return tagEscape(quoteIdent(column))
+}
+
+// quoteIdent quotes name as an SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// tagEscape escapes s for the value of a struct tag.
+func tagEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// jsonName is column with what encoding/json doesn't take in a name, such as
+// commas and quotes, replaced by _.
+func jsonName(column string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r) {
+			return r
+		}
+		return '_'
+	}, column)
+}
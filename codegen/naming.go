@@ -0,0 +1,389 @@
+package codegen
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+// Initialisms are the words of names written in upper case, UserID rather
+// than UserId, and their plurals, UserIDs. They match whole words
+// case-insensitively, so idle stays Idle.
+var Initialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "CSV", "DB", "DNS", "HTML", "HTTP", "HTTPS", "ID", "IP",
+	"JSON", "JWT", "OS", "SQL", "SSH", "SSL", "TCP", "TLS", "TTL", "UI", "URI", "URL", "UTF8", "UUID", "XML",
+}
+
+// LeadingDigits is how names starting with a digit are made identifiers:
+// "spell" spells the digits out, 2fa_enabled becomes TwoFaEnabled, "prefix"
+// puts an X in front, X2faEnabled.
+var LeadingDigits = "spell"
+
+var digitNames = [...]string{"Zero", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine"}
+
+// utils
+func toCamelCase(in string) string {
+	out := joinWords(in)
+	runes := []rune(out)
+	switch {
+	case len(runes) == 0:
+	case unicode.IsDigit(runes[0]) && LeadingDigits == "prefix":
+		out = "X" + out
+	case unicode.IsDigit(runes[0]):
+		var b strings.Builder
+		i := 0
+		for ; i < len(runes) && runes[i] >= '0' && runes[i] <= '9'; i++ {
+			b.WriteString(digitNames[runes[i]-'0'])
+		}
+		if i < len(runes) {
+			runes[i] = unicode.ToUpper(runes[i])
+		}
+		out = b.String() + string(runes[i:])
+	// letters without case, e.g. of CJK scripts, can't make a name exported
+	case unicode.IsLetter(runes[0]) && !unicode.IsUpper(runes[0]):
+		out = "X" + out
+	}
+	return out
+}
+
+// joinWords joins the words of in, capitalized, without making sure the
+// result can start an identifier, as enum constants don't have to.
+func joinWords(in string) string {
+	var b strings.Builder
+	// quoted identifiers may hold anything, runs of what can't be part of
+	// a Go identifier break words like _ does
+	parts := strings.FieldsFunc(asciiFold(in), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, part := range parts {
+		for _, word := range splitWords(part) {
+			if isInitialism(word) {
+				b.WriteString(strings.ToUpper(word))
+				continue
+			}
+			// ids become IDs
+			if len(word) > 2 && strings.HasSuffix(word, "s") && isInitialism(word[:len(word)-1]) {
+				b.WriteString(strings.ToUpper(word[:len(word)-1]) + "s")
+				continue
+			}
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			b.WriteString(string(runes))
+		}
+	}
+	return b.String()
+}
+
+// foldedLetters are the letters that don't decompose into an ASCII letter and
+// diacritics.
+var foldedLetters = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE", "ø", "o", "Ø", "O",
+	"ł", "l", "Ł", "L", "đ", "d", "Đ", "D", "ð", "d", "Ð", "D", "þ", "th", "Þ", "TH",
+)
+
+// asciiFold strips the diacritics of Latin letters, größe becomes grosse.
+// Letters of other scripts are kept.
+func asciiFold(in string) string {
+	var (
+		b    strings.Builder
+		base rune
+	)
+	for _, r := range norm.NFD.String(foldedLetters.Replace(in)) {
+		if !unicode.Is(unicode.Mn, r) {
+			base = r
+		} else if unicode.Is(unicode.Latin, base) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// splitWords splits a camelCase name where a lower case letter or digit is
+// followed by an upper case one, userId into user and Id.
+func splitWords(in string) []string {
+	var (
+		words []string
+		start int
+		prev  rune
+	)
+	for i, r := range in {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			words = append(words, in[start:i])
+			start = i
+		}
+		prev = r
+	}
+	if start < len(in) {
+		words = append(words, in[start:])
+	}
+	return words
+}
+
+func isInitialism(word string) bool {
+	for _, initialism := range Initialisms {
+		if strings.EqualFold(word, initialism) {
+			return true
+		}
+	}
+	return false
+}
+
+// singularIrregular are plurals the suffix rules of singularize get wrong.
+var singularIrregular = map[string]string{
+	"people":   "person",
+	"men":      "man",
+	"women":    "woman",
+	"children": "child",
+	"mice":     "mouse",
+	"geese":    "goose",
+	"feet":     "foot",
+	"teeth":    "tooth",
+	"oxen":     "ox",
+	"indices":  "index",
+	"vertices": "vertex",
+	"matrices": "matrix",
+	"analyses": "analysis",
+	"crises":   "crisis",
+	"theses":   "thesis",
+	"criteria": "criterion",
+	"movies":   "movie",
+	"cookies":  "cookie",
+	"heroes":   "hero",
+	"potatoes": "potato",
+	"tomatoes": "tomato",
+	"echoes":   "echo",
+	"knives":   "knife",
+	"wives":    "wife",
+	"lives":    "life",
+	"wolves":   "wolf",
+	"halves":   "half",
+	"leaves":   "leaf",
+	"shelves":  "shelf",
+	"thieves":  "thief",
+	"selves":   "self",
+	"quizzes":  "quiz",
+	"buses":    "bus",
+	"bonuses":  "bonus",
+	"campuses": "campus",
+	"viruses":  "virus",
+	"censuses": "census",
+	"caches":   "cache",
+}
+
+// singularUncountable are words that are the same in singular and plural, or
+// that end in s without being plural.
+var singularUncountable = map[string]bool{
+	"data":        true,
+	"metadata":    true,
+	"media":       true,
+	"news":        true,
+	"series":      true,
+	"species":     true,
+	"equipment":   true,
+	"information": true,
+	"info":        true,
+	"sheep":       true,
+	"fish":        true,
+	"deer":        true,
+	"aircraft":    true,
+	"software":    true,
+	"feedback":    true,
+	"staff":       true,
+	"alias":       true,
+	"canvas":      true,
+	"gas":         true,
+	"bias":        true,
+	"atlas":       true,
+}
+
+// singularize turns a plural table name into its singular form, e.g.
+// categories into category and people into person. Only the last word of a
+// snake_case name changes.
+func singularize(in string) string {
+	prefix, word := "", in
+	if i := strings.LastIndex(in, "_"); i >= 0 {
+		prefix, word = in[:i+1], in[i+1:]
+	}
+	lower := strings.ToLower(word)
+	if singularUncountable[lower] {
+		return in
+	}
+	if singular, ok := singularIrregular[lower]; ok {
+		// keep the case of the first letter, People becomes Person
+		if word[:1] != lower[:1] {
+			singular = strings.ToUpper(singular[:1]) + singular[1:]
+		}
+		return prefix + singular
+	}
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 4:
+		return prefix + word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "tuses"), strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "zzes"):
+		return prefix + word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return in
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		return prefix + word[:len(word)-1]
+	}
+	return in
+}
+
+// pluralize is the reverse of singularize, order_item becomes order_items.
+// Names that already are plural are returned as they are.
+func pluralize(in string) string {
+	prefix, word := "", in
+	if i := strings.LastIndex(in, "_"); i >= 0 {
+		prefix, word = in[:i+1], in[i+1:]
+	}
+	lower := strings.ToLower(word)
+	if word == "" || singularUncountable[lower] || singularize(word) != word {
+		return in
+	}
+	for plural, singular := range singularIrregular {
+		if singular == lower {
+			if word[:1] != lower[:1] {
+				plural = strings.ToUpper(plural[:1]) + plural[1:]
+			}
+			return prefix + plural
+		}
+	}
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return prefix + word[:len(word)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return prefix + word + "es"
+	}
+	return prefix + word + "s"
+}
+
+// NamingOptions controls how NameModels names models after their tables.
+type NamingOptions struct {
+	Singular      bool
+	StripPrefixes []string
+	StripSuffixes []string
+	// PackagePrefixes are table name prefixes, e.g. auth_, whose tables go to
+	// a package named after them, auth_users to auth.Users
+	PackagePrefixes []string
+	// Names are explicit model names by "table" or "schema.table"
+	Names map[string]string
+	// SchemaCollisions is how tables of the same name in several schemas
+	// are told apart: "prefix" (BillingUsers) or "suffix" (UsersBilling)
+	SchemaCollisions string
+}
+
+// NameModels names models after their tables, with the first matching prefix
+// and suffix stripped and in singular if set, User for tbl_users_t, unless
+// Names has one for them. Tables of the same name in several schemas get the
+// schema added to the name, but for the one in public. A model whose
+// automatic name would clash with another's in its package keeps the plain
+// CamelCase of its table. Models of tables with a PackagePrefixes prefix get
+// their Package set and are named after the rest of the table name.
+func NameModels(models []Model, opts NamingOptions) {
+	schemas := make(map[string]map[string]bool)
+	for _, model := range models {
+		if schemas[model.TableName] == nil {
+			schemas[model.TableName] = make(map[string]bool)
+		}
+		schemas[model.TableName][model.Schema] = true
+	}
+	qualify := func(model Model, name string) string {
+		in := schemas[model.TableName]
+		if len(in) < 2 || model.Schema == "public" {
+			return name
+		}
+		if opts.SchemaCollisions == "suffix" {
+			return name + toCamelCase(model.Schema)
+		}
+		return toCamelCase(model.Schema) + name
+	}
+
+	names := make([]string, len(models))
+	tables := make([]string, len(models))
+	count := make(map[string]int, len(models))
+	explicit := make([]bool, len(models))
+	for i, model := range models {
+		models[i].Package, tables[i] = prefixPackage(model.TableName, opts.PackagePrefixes)
+		names[i] = qualify(model, modelName(tables[i], opts))
+		if name, ok := opts.Names[model.Key()]; ok {
+			names[i], explicit[i] = name, true
+		} else if name, ok := opts.Names[model.TableName]; ok {
+			names[i], explicit[i] = name, true
+		}
+		count[models[i].Package+"."+names[i]]++
+	}
+	for i, model := range models {
+		plain := qualify(model, toCamelCase(tables[i]))
+		if count[model.Package+"."+names[i]] > 1 && names[i] != plain && !explicit[i] {
+			report.Debug.Printf("table %s: keeping model name %s, %s is taken", model.Key(), plain, names[i])
+			names[i] = plain
+		}
+		models[i].Name = names[i]
+	}
+}
+
+// prefixPackage returns the package of table by the first of prefixes it has
+// and the rest of its name, or "" and table.
+func prefixPackage(table string, prefixes []string) (string, string) {
+	for _, prefix := range prefixes {
+		if rest := strings.TrimPrefix(table, prefix); rest != table && rest != "" && !unicode.IsDigit([]rune(rest)[0]) {
+			return packageName(strings.Trim(prefix, "_")), rest
+		}
+	}
+	return "", table
+}
+
+func modelName(table string, opts NamingOptions) string {
+	name := table
+	for _, prefix := range opts.StripPrefixes {
+		// a name must be left, and one that can start an identifier
+		if rest := strings.TrimPrefix(name, prefix); rest != name && rest != "" && !unicode.IsDigit([]rune(rest)[0]) {
+			name = rest
+			break
+		}
+	}
+	for _, suffix := range opts.StripSuffixes {
+		if rest := strings.TrimSuffix(name, suffix); rest != name && rest != "" {
+			name = rest
+			break
+		}
+	}
+	if opts.Singular {
+		name = singularize(name)
+	}
+	return toCamelCase(name)
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, item := range in {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func toLowerCamelCase(in string) string {
+	runes := []rune(toCamelCase(in))
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
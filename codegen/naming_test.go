@@ -0,0 +1,206 @@
+package codegen
+
+import "testing"
+
+func TestToCamelCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"users", "Users"},
+		{"user_id", "UserID"},
+		{"user_ids", "UserIDs"},
+		{"idle", "Idle"},
+		{"api_url", "APIURL"},
+		{"html_body", "HTMLBody"},
+		{"createdAt", "CreatedAt"},
+		{"order-items", "OrderItems"},
+		{"Größe", "Grosse"},
+		{"2fa_enabled", "TwoFaEnabled"},
+		{"3d", "ThreeD"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := toCamelCase(tt.in); got != tt.want {
+			t.Errorf("toCamelCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToCamelCaseLeadingDigitsPrefix(t *testing.T) {
+	defer func(old string) { LeadingDigits = old }(LeadingDigits)
+	LeadingDigits = "prefix"
+	if got := toCamelCase("2fa_enabled"); got != "X2faEnabled" {
+		t.Errorf("toCamelCase(%q) = %q, want %q", "2fa_enabled", got, "X2faEnabled")
+	}
+}
+
+func TestToCamelCaseInitialisms(t *testing.T) {
+	defer func(old []string) { Initialisms = old }(Initialisms)
+	Initialisms = []string{"SKU"}
+	tests := []struct {
+		in, want string
+	}{
+		{"sku", "SKU"},
+		{"skus", "SKUs"},
+		{"user_id", "UserId"},
+	}
+	for _, tt := range tests {
+		if got := toCamelCase(tt.in); got != tt.want {
+			t.Errorf("toCamelCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToLowerCamelCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"users", "users"},
+		{"user_id", "userID"},
+		{"id", "id"},
+		{"api_key", "apiKey"},
+	}
+	for _, tt := range tests {
+		if got := toLowerCamelCase(tt.in); got != tt.want {
+			t.Errorf("toLowerCamelCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSingularize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"users", "user"},
+		{"categories", "category"},
+		{"people", "person"},
+		{"People", "Person"},
+		{"addresses", "address"},
+		{"boxes", "box"},
+		{"branches", "branch"},
+		{"statuses", "status"},
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"order_items", "order_item"},
+		{"user_data", "user_data"},
+		{"series", "series"},
+		{"s", "s"},
+	}
+	for _, tt := range tests {
+		if got := singularize(tt.in); got != tt.want {
+			t.Errorf("singularize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"user", "users"},
+		{"users", "users"},
+		{"category", "categories"},
+		{"day", "days"},
+		{"person", "people"},
+		{"box", "boxes"},
+		{"branch", "branches"},
+		{"order_item", "order_items"},
+		{"series", "series"},
+	}
+	for _, tt := range tests {
+		if got := pluralize(tt.in); got != tt.want {
+			t.Errorf("pluralize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNameModels(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []string
+		opts   NamingOptions
+		want   []string
+	}{
+		{
+			name:   "plain",
+			tables: []string{"public.users", "public.order_items"},
+			want:   []string{"Users", "OrderItems"},
+		},
+		{
+			name:   "singular",
+			tables: []string{"public.users", "public.categories"},
+			opts:   NamingOptions{Singular: true},
+			want:   []string{"User", "Category"},
+		},
+		{
+			name:   "strip prefix and suffix",
+			tables: []string{"public.tbl_users_t"},
+			opts:   NamingOptions{Singular: true, StripPrefixes: []string{"tbl_"}, StripSuffixes: []string{"_t"}},
+			want:   []string{"User"},
+		},
+		{
+			name:   "prefix leaving a digit is kept",
+			tables: []string{"public.tbl_2fa"},
+			opts:   NamingOptions{StripPrefixes: []string{"tbl_"}},
+			want:   []string{"Tbl2fa"},
+		},
+		{
+			name:   "schema collisions prefix",
+			tables: []string{"public.users", "billing.users"},
+			opts:   NamingOptions{SchemaCollisions: "prefix"},
+			want:   []string{"Users", "BillingUsers"},
+		},
+		{
+			name:   "schema collisions suffix",
+			tables: []string{"public.users", "billing.users"},
+			opts:   NamingOptions{SchemaCollisions: "suffix"},
+			want:   []string{"Users", "UsersBilling"},
+		},
+		{
+			name:   "clashing singulars keep their table name",
+			tables: []string{"public.user", "public.users"},
+			opts:   NamingOptions{Singular: true},
+			want:   []string{"User", "Users"},
+		},
+		{
+			name:   "explicit names",
+			tables: []string{"public.ppl_rec", "billing.x"},
+			opts:   NamingOptions{Names: map[string]string{"ppl_rec": "Person", "billing.x": "Invoice"}},
+			want:   []string{"Person", "Invoice"},
+		},
+		{
+			name:   "package prefixes",
+			tables: []string{"public.auth_users", "public.users"},
+			opts:   NamingOptions{PackagePrefixes: []string{"auth_"}},
+			want:   []string{"auth.Users", "Users"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := make([]Model, len(tt.tables))
+			for i, key := range tt.tables {
+				models[i] = testModel(key)
+			}
+			NameModels(models, tt.opts)
+			for i, model := range models {
+				got := model.Name
+				if model.Package != "" {
+					got = model.Package + "." + got
+				}
+				if got != tt.want[i] {
+					t.Errorf("table %s: got %q, want %q", tt.tables[i], got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// testModel returns a model of the table "schema.table" without fields.
+func testModel(key string) Model {
+	for i := range key {
+		if key[i] == '.' {
+			return Model{Schema: key[:i], TableName: key[i+1:]}
+		}
+	}
+	return Model{Schema: "public", TableName: key}
+}
@@ -0,0 +1,301 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+// commentPrefixes are the line comment markers of the output formats that
+// support comments, keyed by file extension.
+var commentPrefixes = map[string]string{
+	".go":      "// ",
+	".proto":   "// ",
+	".ts":      "// ",
+	".dot":     "// ",
+	".graphql": "# ",
+	".mmd":     "%% ",
+}
+
+// GenerationInfo describes how a set of files was generated. Generator is the
+// version of the generator, as it should appear in headers.
+type GenerationInfo struct {
+	Generator string
+	Database  string
+	Schema    string
+	Command   []string
+	// Source is the schema the files were generated from, it doesn't show
+	// in headers.
+	Source *introspect.Schema
+}
+
+// Header returns the canonical "Code generated ... DO NOT EDIT." comment in
+// the comment syntax of fileName, or nil for formats without comments.
+func (info GenerationInfo) Header(fileName string) []byte {
+	lines := []string{
+		fmt.Sprintf("Code generated by postgres-model-generator %s from database %q, schema %q. DO NOT EDIT.",
+			info.Generator, info.Database, info.Schema),
+		"Command: " + strings.Join(info.Command, " "),
+	}
+
+	var buf bytes.Buffer
+	ext := filepath.Ext(fileName)
+	if prefix, ok := commentPrefixes[ext]; ok {
+		for _, line := range lines {
+			buf.WriteString(prefix + line + "\n")
+		}
+	} else if ext == ".md" {
+		for _, line := range lines {
+			buf.WriteString("<!-- " + line + " -->\n")
+		}
+	} else {
+		return nil
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// isGenerated reports whether the file at path is missing or carries a
+// "Code generated ... DO NOT EDIT." comment before its package clause or
+// within its first lines, i.e. whether it's safe to write.
+func isGenerated(path string) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 0; n < 20 && scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.Contains(line, "Code generated ") && strings.Contains(line, "DO NOT EDIT.") {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false, scanner.Err()
+}
+
+// OutputFile is a rendered file, named relative to the output directory.
+type OutputFile struct {
+	Name    string
+	Content []byte
+	// Table is the "schema.table" key of the one model a per-model file
+	// holds, empty for other files.
+	Table string
+}
+
+// tableFileNames returns the names of the files formats writing one per
+// table give models, the table name followed by ext. Tables named like one
+// in another schema are prefixed with their schema and a dot outside public,
+// so their files don't overwrite each other.
+func tableFileNames(models []Model, ext string) []string {
+	tables := make(map[string]int, len(models))
+	for _, model := range models {
+		tables[model.TableName]++
+	}
+	names := make([]string, len(models))
+	for i, model := range models {
+		names[i] = model.TableName + ext
+		if tables[model.TableName] > 1 && model.Schema != "public" {
+			names[i] = model.Schema + "." + names[i]
+		}
+	}
+	return names
+}
+
+// WriteFiles writes files below dir, each prefixed with the generated-code
+// header, creating missing directories. Unless force is set, nothing is
+// written if any file would replace one without the header. Formats without
+// comments (json) carry no header and are always overwritten.
+func WriteFiles(dir string, files []OutputFile, info GenerationInfo, force bool) error {
+	if err := CheckFiles(dir, files, info, force); err != nil {
+		return err
+	}
+	report.Steps.Start("writing files", len(files))
+	defer report.Steps.Finish()
+	return forEach(len(files), func(i int) error {
+		if err := WriteFile(dir, files[i], info, force); err != nil {
+			return err
+		}
+		report.Steps.Add(1)
+		return nil
+	})
+}
+
+// CheckFiles returns an error if, without force, any of files would replace
+// one below dir that isn't generated. Only the names of files are used.
+func CheckFiles(dir string, files []OutputFile, info GenerationInfo, force bool) error {
+	for _, file := range files {
+		target := filepath.Join(dir, file.Name)
+		if force || info.Header(file.Name) == nil {
+			continue
+		}
+		if generated, err := isGenerated(target); err != nil {
+			return err
+		} else if !generated {
+			return fmt.Errorf("%s exists and is not generated, refusing to overwrite it (use -force to do so anyway)", target)
+		}
+	}
+	return nil
+}
+
+// WriteFile writes a single file like WriteFiles does, after CheckFiles.
+func WriteFile(dir string, file OutputFile, info GenerationInfo, force bool) error {
+	target := filepath.Join(dir, file.Name)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	content, err := fileContent(target, file, info, force)
+	if err != nil {
+		return err
+	}
+	// unchanged files keep their mtime, so that builds stay cached
+	if current, err := ioutil.ReadFile(target); err == nil && bytes.Equal(current, content) {
+		report.Debug.Printf("unchanged %s", target)
+		return nil
+	}
+	if err := ioutil.WriteFile(target, content, 0644); err != nil {
+		return err
+	}
+	report.Debug.Printf("wrote %s", target)
+	return nil
+}
+
+// Staging writes files like WriteFile does, but to temporary files next to
+// their targets that only replace them on Commit. Files rendered one by one
+// are staged so that an error rendering a later one leaves dir as it was.
+// Its methods may be called concurrently.
+type Staging struct {
+	dir   string
+	info  GenerationInfo
+	force bool
+
+	mu     sync.Mutex
+	staged map[string]string
+}
+
+// NewStaging returns a Staging for files below dir, after CheckFiles.
+func NewStaging(dir string, info GenerationInfo, force bool) *Staging {
+	return &Staging{dir: dir, info: info, force: force, staged: make(map[string]string)}
+}
+
+// Write stages file, unless its content is unchanged.
+func (s *Staging) Write(file OutputFile) error {
+	target := filepath.Join(s.dir, file.Name)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	content, err := fileContent(target, file, s.info, s.force)
+	if err != nil {
+		return err
+	}
+	if current, err := ioutil.ReadFile(target); err == nil && bytes.Equal(current, content) {
+		report.Debug.Printf("unchanged %s", target)
+		return nil
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.staged[target] = tmp.Name()
+	s.mu.Unlock()
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(tmp.Name(), 0644)
+}
+
+// Commit moves the staged files into place.
+func (s *Staging) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	targets := make([]string, 0, len(s.staged))
+	for target := range s.staged {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		if err := os.Rename(s.staged[target], target); err != nil {
+			return err
+		}
+		delete(s.staged, target)
+		report.Debug.Printf("wrote %s", target)
+	}
+	return nil
+}
+
+// Discard removes the staged files, leaving their targets alone.
+func (s *Staging) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for target, tmp := range s.staged {
+		os.Remove(tmp)
+		delete(s.staged, target)
+	}
+}
+
+// PreviewFiles writes a unified diff between the files below dir and what
+// WriteFiles would write instead of them, and reports whether there is any.
+func PreviewFiles(w io.Writer, dir string, files []OutputFile, info GenerationInfo) (bool, error) {
+	changed := false
+	for _, file := range files {
+		target := filepath.Join(dir, file.Name)
+		current, err := ioutil.ReadFile(target)
+		fromFile := target
+		if os.IsNotExist(err) {
+			fromFile = "/dev/null"
+		} else if err != nil {
+			return false, err
+		}
+
+		// custom code that generate would refuse to drop shows up as removed
+		content, err := fileContent(target, file, info, true)
+		if err != nil {
+			return false, err
+		}
+		var before []string
+		if len(current) > 0 {
+			before = difflib.SplitLines(string(current))
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        before,
+			B:        difflib.SplitLines(string(content)),
+			FromFile: fromFile,
+			ToFile:   target,
+			Context:  3,
+		})
+		if err != nil {
+			return false, err
+		}
+		if diff == "" {
+			continue
+		}
+		changed = true
+		if _, err := io.WriteString(w, diff); err != nil {
+			return false, err
+		}
+	}
+	return changed, nil
+}
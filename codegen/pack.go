@@ -0,0 +1,171 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/imports"
+	"gopkg.in/yaml.v3"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+// Pack is a template pack, a directory holding a pack.yaml manifest and the
+// *.tmpl files it renders. All templates of a pack are parsed together, so
+// they can share {{define}}d parts. The built-in "enum" template renders an
+// enum type like the go format does, "enum_text" its -enum-text methods,
+// "json_struct" a JSONStruct and "joins" the -join-helpers of a model, a pack
+// may define its own instead.
+type Pack struct {
+	Name    string     `yaml:"name"`
+	Version string     `yaml:"version"`
+	Files   []PackFile `yaml:"files"`
+
+	tmpl *template.Template
+}
+
+// PackFile is a file a pack renders. Output names it and is a template
+// itself, with PerModel it is rendered once for every model.
+type PackFile struct {
+	Template string `yaml:"template"`
+	Output   string `yaml:"output"`
+	PerModel bool   `yaml:"per_model"`
+}
+
+// PackData is what the templates of a pack are executed with. Model is only
+// set for PerModel files, Imports are the paths the models' types need.
+type PackData struct {
+	Models  []Model
+	Model   Model
+	Enums   []Enum
+	Options GoOptions
+	Imports []string
+}
+
+var packFuncs = template.FuncMap{
+	"camel":      toCamelCase,
+	"lowerCamel": toLowerCamelCase,
+	"singular":   singularize,
+	"plural":     pluralize,
+	"join":       strings.Join,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+// LoadPack reads the manifest and templates of the pack in dir.
+func LoadPack(dir string) (*Pack, error) {
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, "pack.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var pack Pack
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&pack); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, "pack.yaml"), err)
+	}
+	if len(pack.Files) == 0 {
+		return nil, fmt.Errorf("template pack %s renders no files", dir)
+	}
+
+	pack.tmpl = template.New("").Funcs(packFuncs)
+	if _, err := pack.tmpl.New("enum").Parse(enumTpl); err != nil {
+		return nil, err
+	}
+	if _, err := pack.tmpl.New("enum_text").Parse(enumTextTpl); err != nil {
+		return nil, err
+	}
+	if _, err := pack.tmpl.New("json_struct").Parse(jsonStructTpl); err != nil {
+		return nil, err
+	}
+	if _, err := pack.tmpl.New("joins").Parse(joinTpl); err != nil {
+		return nil, err
+	}
+	if _, err := pack.tmpl.ParseGlob(filepath.Join(dir, "*.tmpl")); err != nil {
+		return nil, err
+	}
+	for _, file := range pack.Files {
+		if pack.tmpl.Lookup(file.Template) == nil {
+			return nil, fmt.Errorf("template pack %s: no template %s", dir, file.Template)
+		}
+	}
+	return &pack, nil
+}
+
+// Render executes the pack's templates. Go files are formatted and get their
+// imports fixed like the go format's.
+func (p *Pack) Render(models []Model, enums []Enum, opts GoOptions) ([]OutputFile, error) {
+	var importPaths []string
+	for _, model := range models {
+		for _, field := range model.Fields {
+			if field.Type == "" {
+				return nil, fmt.Errorf("%s.%s: type not detected for %s", model.Key(), field.Column.ColumnName, field.Column.UDTName)
+			}
+			if field.Import != "" {
+				importPaths = append(importPaths, field.Import)
+			}
+		}
+	}
+	data := PackData{
+		Models:  models,
+		Enums:   enums,
+		Options: opts,
+		Imports: uniqueStrings(append(importPaths, opts.Imports...)),
+	}
+
+	var files []OutputFile
+	for _, file := range p.Files {
+		if !file.PerModel {
+			out, err := p.render(file, data)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, out)
+			continue
+		}
+		outs := make([]OutputFile, len(models))
+		err := forEach(len(models), func(i int) error {
+			modelData := data
+			modelData.Model = models[i]
+			out, err := p.render(file, modelData)
+			if err != nil {
+				return fmt.Errorf("%s: %w", models[i].Key(), err)
+			}
+			out.Table = models[i].Key()
+			outs[i] = out
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, outs...)
+	}
+	report.Steps.Add(len(models))
+	return files, nil
+}
+
+func (p *Pack) render(file PackFile, data PackData) (OutputFile, error) {
+	nameTmpl, err := template.New("output").Funcs(packFuncs).Parse(file.Output)
+	if err != nil {
+		return OutputFile{}, err
+	}
+	var name, content bytes.Buffer
+	if err := nameTmpl.Execute(&name, data); err != nil {
+		return OutputFile{}, err
+	}
+	if err := p.tmpl.ExecuteTemplate(&content, file.Template, data); err != nil {
+		return OutputFile{}, err
+	}
+
+	out := OutputFile{Name: name.String(), Content: content.Bytes()}
+	if filepath.Ext(out.Name) == ".go" {
+		if out.Content, err = imports.Process(out.Name, out.Content, nil); err != nil {
+			return OutputFile{}, fmt.Errorf("%s: %w", out.Name, err)
+		}
+	}
+	return out, nil
+}
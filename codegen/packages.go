@@ -0,0 +1,210 @@
+package codegen
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+// packageName makes a Go package name of a schema name.
+func packageName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(asciiFold(name)))
+	if name == "" || name[0] >= '0' && name[0] <= '9' || token.IsKeyword(name) {
+		name = "x" + name
+	}
+	return name
+}
+
+// assignPackages returns models with Package set: the models of tables
+// matching the patterns of a package in Packages go to the first such one in
+// path order, others keep the one NameModels gave them by table prefix, and
+// with SchemaPackages the rest outside public go to a package named after
+// their schema.
+func assignPackages(models []Model, opts GoOptions) []Model {
+	if !opts.SchemaPackages && len(opts.Packages) == 0 {
+		return models
+	}
+	pkgs := make([]string, 0, len(opts.Packages))
+	for pkg := range opts.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	out := make([]Model, len(models))
+	for i, model := range models {
+		for _, pkg := range pkgs {
+			if introspect.TableIncluded(model.Schema, model.TableName, opts.Packages[pkg], nil) {
+				model.Package = pkg
+				break
+			}
+		}
+		if model.Package == "" && opts.SchemaPackages && model.Schema != "" && model.Schema != "public" {
+			model.Package = packageName(model.Schema)
+		}
+		out[i] = model
+	}
+	return out
+}
+
+// packageImport returns the import path of the package pkg of the output.
+func packageImport(pkg string, opts GoOptions) (string, error) {
+	if opts.ImportPath == "" {
+		return "", fmt.Errorf("the import path of the output package is unknown, set -import-path or go.import_path")
+	}
+	if pkg == "" {
+		return opts.ImportPath, nil
+	}
+	return opts.ImportPath + "/" + pkg, nil
+}
+
+// qualifyPackages returns models with the types they use from other packages
+// qualified and imported: enums, which are declared in the output package,
+// and the models of has-many fields. Packages importing each other are an
+// error, Go has no import cycles.
+func qualifyPackages(models []Model, enums []Enum, opts GoOptions) ([]Model, error) {
+	packages := make(map[string]string, len(models))
+	for _, model := range models {
+		packages[model.Key()] = model.Package
+	}
+	enumNames := make(map[string]bool, len(enums))
+	for _, enum := range enums {
+		enumNames[enum.Name] = true
+	}
+	qualifier := func(pkg string) string {
+		if pkg == "" {
+			return opts.Package
+		}
+		return path.Base(pkg)
+	}
+
+	imports := make(map[string]map[string]bool)
+	use := func(from, to string) (string, error) {
+		if imports[from] == nil {
+			imports[from] = make(map[string]bool)
+		}
+		imports[from][to] = true
+		return packageImport(to, opts)
+	}
+	out := make([]Model, len(models))
+	for i, model := range models {
+		var err error
+		fields := make([]Field, len(model.Fields))
+		for j, field := range model.Fields {
+			base := strings.TrimLeft(field.Type, "*[]")
+			if model.Package != "" && enumNames[base] {
+				field.Type = strings.TrimSuffix(field.Type, base) + qualifier("") + "." + base
+				if field.Import, err = use(model.Package, ""); err != nil {
+					return nil, err
+				}
+			}
+			fields[j] = field
+		}
+		model.Fields = fields
+
+		relations := make([]Relation, len(model.Relations))
+		for j, rel := range model.Relations {
+			pkg := packages[rel.Schema+"."+rel.TableName]
+			if rel.Field && pkg != model.Package {
+				rel.Model = qualifier(pkg) + "." + rel.Model
+				if rel.Import, err = use(model.Package, pkg); err != nil {
+					return nil, err
+				}
+			}
+			relations[j] = rel
+		}
+		model.Relations = relations
+		out[i] = model
+	}
+
+	// a package importing itself through others is a cycle
+	var visit func(pkg string, seen []string) error
+	visit = func(pkg string, seen []string) error {
+		for i, p := range seen {
+			if p == pkg {
+				cycle := append(append([]string(nil), seen[i:]...), pkg)
+				for k := range cycle {
+					cycle[k] = qualifier(cycle[k])
+				}
+				return fmt.Errorf("packages would import each other: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		var next []string
+		for to := range imports[pkg] {
+			next = append(next, to)
+		}
+		sort.Strings(next)
+		for _, to := range next {
+			if err := visit(to, append(seen, pkg)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var from []string
+	for pkg := range imports {
+		from = append(from, pkg)
+	}
+	sort.Strings(from)
+	for _, pkg := range from {
+		if err := visit(pkg, nil); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// packageModels assigns models their packages and qualifies what they use
+// across packages.
+func packageModels(models []Model, enums []Enum, opts GoOptions) ([]Model, error) {
+	return qualifyPackages(assignPackages(models, opts), enums, opts)
+}
+
+// packages returns the packages of models, the output package first, which
+// is always there.
+func packages(models []Model) []string {
+	seen := map[string]bool{"": true}
+	pkgs := []string{""}
+	for _, model := range models {
+		if !seen[model.Package] {
+			seen[model.Package] = true
+			pkgs = append(pkgs, model.Package)
+		}
+	}
+	sort.Strings(pkgs[1:])
+	return pkgs
+}
+
+// inPackage returns the models of pkg.
+func inPackage(models []Model, pkg string) []Model {
+	var in []Model
+	for _, model := range models {
+		if model.Package == pkg {
+			in = append(in, model)
+		}
+	}
+	return in
+}
+
+// filePackage returns the package a file of GoFiles belongs to.
+func filePackage(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// packageOptions returns opts for the files of pkg.
+func packageOptions(pkg string, opts GoOptions) GoOptions {
+	if pkg != "" {
+		opts.Package = path.Base(pkg)
+	}
+	return opts
+}
@@ -0,0 +1,54 @@
+package codegen
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Workers bounds how many files RenderGo, Pack.Render and WriteFiles work on
+// at once. Their results don't depend on it.
+var Workers = runtime.NumCPU()
+
+// forEach calls fn with 0 to n-1 on up to Workers goroutines. It returns the
+// error of the lowest index that failed, the one a loop would return.
+func forEach(n int, fn func(i int) error) error {
+	workers := Workers
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		errs = make([]error, n)
+		next = int64(-1)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const protoTpl = `syntax = "proto3";
+
+package models;
+{{if .Imports}}
+{{range .Imports}}import "{{.}}";
+{{end}}{{end}}
+{{range .Enums}}enum {{.Name}} {
+{{range $i, $v := .Values}}  {{$v}} = {{$i}};
+{{end}}}
+
+{{end}}{{range $i, $m := .Messages}}{{if $i}}
+{{end}}message {{$m.Name}} {
+{{range $m.Fields}}  {{.Type}} {{.Name}} = {{.Number}};
+{{end}}}
+{{end}}`
+
+type ProtoFile struct {
+	Imports  []string
+	Enums    []ProtoEnum
+	Messages []ProtoMessage
+}
+
+// ProtoEnum is an enum type, Values numbered from 0, the unspecified value.
+type ProtoEnum struct {
+	Name   string
+	Values []string
+}
+
+type ProtoMessage struct {
+	Name   string
+	Fields []ProtoField
+}
+
+type ProtoField struct {
+	Name   string
+	Type   string
+	Number int
+}
+
+var (
+	protoScalars = map[string]string{
+		"bool":        "bool",
+		"int2":        "int32",
+		"int4":        "int32",
+		"int8":        "int64",
+		"float4":      "float",
+		"float8":      "double",
+		"numeric":     "string",
+		"varchar":     "string",
+		"text":        "string",
+		"bpchar":      "string",
+		"uuid":        "string",
+		"tsvector":    "string",
+		"tsquery":     "string",
+		"bytea":       "bytes",
+		"timestamp":   "google.protobuf.Timestamp",
+		"timestamptz": "google.protobuf.Timestamp",
+		"date":        "google.protobuf.Timestamp",
+		"json":        "google.protobuf.Value",
+		"jsonb":       "google.protobuf.Value",
+	}
+
+	protoWrappers = map[string]string{
+		"bool":   "google.protobuf.BoolValue",
+		"int32":  "google.protobuf.Int32Value",
+		"int64":  "google.protobuf.Int64Value",
+		"float":  "google.protobuf.FloatValue",
+		"double": "google.protobuf.DoubleValue",
+		"string": "google.protobuf.StringValue",
+		"bytes":  "google.protobuf.BytesValue",
+	}
+
+	protoImports = map[string]string{
+		"google.protobuf.Timestamp": "google/protobuf/timestamp.proto",
+		"google.protobuf.Value":     "google/protobuf/struct.proto",
+	}
+)
+
+// protoEnum returns the proto enum of type name with labels. Enum values
+// share the scope of the enum, so they're prefixed with its name in upper
+// case, as the style guide asks. The unspecified value comes first, standing
+// in for NULL.
+func protoEnum(name string, labels []string) ProtoEnum {
+	prefix := capsWords(name) + "_"
+	enum := ProtoEnum{Name: toCamelCase(name), Values: []string{prefix + "UNSPECIFIED"}}
+	taken := map[string]bool{enum.Values[0]: true}
+	for i, label := range labels {
+		words := capsWords(label)
+		if words == "" {
+			words = "VALUE_" + strconv.Itoa(i+1)
+		}
+		value := prefix + words
+		for n := 2; taken[value]; n++ {
+			value = prefix + words + "_" + strconv.Itoa(n)
+		}
+		taken[value] = true
+		enum.Values = append(enum.Values, value)
+	}
+	return enum
+}
+
+// protoFieldName returns column in snake case, the style of proto field
+// names, falling back to a name by position where that is no identifier.
+func protoFieldName(col introspect.DBColumn) string {
+	name := strings.ToLower(capsWords(col.ColumnName))
+	switch {
+	case name == "":
+		return "field_" + strconv.Itoa(col.OrdinalPosition)
+	case name[0] >= '0' && name[0] <= '9':
+		return "field_" + name
+	}
+	return name
+}
+
+// protoType maps a column to a proto field type. Nullable scalars are mapped
+// to wrapper types so that NULL stays distinguishable from the zero value,
+// enums have their unspecified value for it.
+func protoType(col introspect.DBColumn, enums map[string]ProtoEnum) (string, error) {
+	udt, isArray := elementType(col.UDTName)
+
+	t, ok := protoScalars[udt]
+	if enum, isEnum := enums[udt]; isEnum {
+		t, ok = enum.Name, true
+	}
+	if !ok {
+		return "", fmt.Errorf("proto type not detected for %s", col.UDTName)
+	}
+	if isArray {
+		return "repeated " + t, nil
+	}
+	if wrapper, ok := protoWrappers[t]; ok && col.IsNullable {
+		return wrapper, nil
+	}
+	return t, nil
+}
+
+// RenderProto renders one message per model. Field numbers are taken from the
+// column ordinal positions, which postgres never reuses, so numbering stays
+// stable across runs and schema changes. Field names are the columns' in
+// snake case, enum columns get an enum declared.
+func RenderProto(models []Model, dbEnums introspect.DBEnums) ([]byte, error) {
+	var (
+		file    ProtoFile
+		imports = make(map[string]bool)
+		enums   = make(map[string]ProtoEnum, len(dbEnums))
+		used    = make(map[string]bool)
+	)
+	names := make(map[string]bool, len(models))
+	for _, model := range models {
+		names[model.Name] = true
+	}
+	for typeName, labels := range dbEnums {
+		enum := protoEnum(typeName, labels)
+		// enums named like a message leave the name to it
+		if names[enum.Name] {
+			enum.Name += "Enum"
+		}
+		enums[typeName] = enum
+	}
+
+	for _, model := range models {
+		report.Steps.Add(1)
+		msg := ProtoMessage{Name: model.Name}
+		taken := make(map[string]bool, len(model.Fields))
+		for _, field := range model.Fields {
+			t, err := protoType(field.Column, enums)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", model.Key(), field.Column.ColumnName, err)
+			}
+			if udt, _ := elementType(field.Column.UDTName); enums[udt].Name != "" {
+				used[udt] = true
+			}
+			name := protoFieldName(field.Column)
+			for n := 2; taken[name]; n++ {
+				name = protoFieldName(field.Column) + "_" + strconv.Itoa(n)
+			}
+			taken[name] = true
+			elem := strings.TrimPrefix(t, "repeated ")
+			if imp, ok := protoImports[elem]; ok {
+				imports[imp] = true
+			} else if strings.HasPrefix(elem, "google.protobuf.") {
+				imports["google/protobuf/wrappers.proto"] = true
+			}
+			msg.Fields = append(msg.Fields, ProtoField{
+				Name:   name,
+				Type:   t,
+				Number: field.Column.OrdinalPosition,
+			})
+		}
+		file.Messages = append(file.Messages, msg)
+	}
+
+	for imp := range imports {
+		file.Imports = append(file.Imports, imp)
+	}
+	sort.Strings(file.Imports)
+	for typeName := range used {
+		file.Enums = append(file.Enums, enums[typeName])
+	}
+	sort.Slice(file.Enums, func(i, j int) bool {
+		return file.Enums[i].Name < file.Enums[j].Name
+	})
+
+	tmpl, err := template.New("proto").Parse(protoTpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
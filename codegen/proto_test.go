@@ -0,0 +1,56 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+func TestRenderProto(t *testing.T) {
+	s, err := introspect.ParseDDL(`
+		CREATE TYPE mood AS ENUM ('sad', 'in-progress', 'in_progress', '+');
+		CREATE TABLE users (
+			id integer PRIMARY KEY,
+			"2fa_enabled" bool NOT NULL,
+			"UserName" text,
+			user_name text,
+			mood mood,
+			moods mood[]
+		);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := BuildModels(s.Tables, typemap.Fallback("string"))
+	NameModels(models, NamingOptions{Singular: true})
+	content, err := RenderProto(models, s.Enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `syntax = "proto3";
+
+package models;
+
+import "google/protobuf/wrappers.proto";
+
+enum Mood {
+  MOOD_UNSPECIFIED = 0;
+  MOOD_SAD = 1;
+  MOOD_IN_PROGRESS = 2;
+  MOOD_IN_PROGRESS_2 = 3;
+  MOOD_VALUE_4 = 4;
+}
+
+message User {
+  int32 id = 1;
+  bool field_2fa_enabled = 2;
+  google.protobuf.StringValue user_name = 3;
+  google.protobuf.StringValue user_name_2 = 4;
+  Mood mood = 5;
+  repeated Mood moods = 6;
+}
+`
+	if got := string(content); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"golang.org/x/tools/imports"
+)
+
+var (
+	regionBegin = regexp.MustCompile(`^\s*// -- custom (\S+) --\s*$`)
+	regionEnd   = regexp.MustCompile(`^\s*// -- end custom (\S+) --\s*$`)
+)
+
+// customRegions returns the code between the custom region markers of
+// content, keyed by region name.
+func customRegions(content []byte) (map[string][]byte, error) {
+	regions := make(map[string][]byte)
+	var (
+		name string
+		body bytes.Buffer
+	)
+	for i, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if m := regionBegin.FindSubmatch(line); m != nil {
+			if name != "" {
+				return nil, fmt.Errorf("line %d: custom region %s starts inside region %s", i+1, m[1], name)
+			}
+			name = string(m[1])
+			body.Reset()
+			continue
+		}
+		if m := regionEnd.FindSubmatch(line); m != nil {
+			if string(m[1]) != name {
+				return nil, fmt.Errorf("line %d: end of custom region %s outside of it", i+1, m[1])
+			}
+			regions[name] = append([]byte(nil), body.Bytes()...)
+			name = ""
+			continue
+		}
+		if name != "" {
+			body.Write(line)
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("custom region %s is not closed", name)
+	}
+	return regions, nil
+}
+
+// fillRegions copies the custom code of regions into the same named regions
+// of content.
+func fillRegions(content []byte, regions map[string][]byte) []byte {
+	var (
+		out  bytes.Buffer
+		skip bool
+	)
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if m := regionBegin.FindSubmatch(line); m != nil {
+			out.Write(line)
+			out.Write(regions[string(m[1])])
+			skip = true
+			continue
+		}
+		if regionEnd.Match(line) {
+			skip = false
+		}
+		if !skip {
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+// fileContent is what file is written as: the generated-code header, the
+// rendered content and the custom code found in the current file at path.
+// Custom code of regions that are no longer generated, e.g. for a dropped
+// table, is an error unless force is set, it would be lost otherwise.
+func fileContent(path string, file OutputFile, info GenerationInfo, force bool) ([]byte, error) {
+	content := append(info.Header(file.Name), file.Content...)
+
+	current, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return content, nil
+	} else if err != nil {
+		return nil, err
+	}
+	old, err := customRegions(current)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(old) == 0 {
+		return content, nil
+	}
+	regions, err := customRegions(content)
+	if err != nil {
+		return nil, err
+	}
+	for name, code := range old {
+		if _, ok := regions[name]; !ok && len(bytes.TrimSpace(code)) > 0 && !force {
+			return nil, fmt.Errorf("%s: custom region %s is no longer generated, its code would be lost (use -force to drop it)", path, name)
+		}
+	}
+
+	content = fillRegions(content, old)
+	if filepath.Ext(path) == ".go" {
+		// custom code may need imports the generated code doesn't
+		return imports.Process(path, content, nil)
+	}
+	return content, nil
+}
@@ -0,0 +1,18 @@
+package codegen
+
+const registryTpl = `
+// RegistryEntry is a model of the package: its table, its type and a function
+// allocating a new one.
+type RegistryEntry struct {
+	Table string
+	Type  reflect.Type
+	New   func() interface{}
+}
+
+// Registry holds the models of the package by table, qualified outside of
+// public, for code working with all of them such as migrators, admin UIs or
+// fixture loaders.
+var Registry = map[string]RegistryEntry{
+{{range .}}	{{printf "%q" .SQLName}}: {Table: {{printf "%q" .SQLName}}, Type: reflect.TypeOf({{.Name}}{}), New: func() interface{} { return new({{.Name}}) }},
+{{end}}}
+`
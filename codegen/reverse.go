@@ -0,0 +1,424 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+// reverseTypes maps Go types of model fields to column types, the reverse
+// of the built-in type mapping with the widest type where there's a choice.
+var reverseTypes = map[string]string{
+	"string":          "text",
+	"int":             "int8",
+	"int64":           "int8",
+	"int32":           "int4",
+	"int16":           "int2",
+	"int8":            "int2",
+	"uint":            "int8",
+	"uint64":          "int8",
+	"uint32":          "int8",
+	"uint16":          "int4",
+	"uint8":           "int2",
+	"bool":            "bool",
+	"float64":         "float8",
+	"float32":         "float4",
+	"time.Time":       "timestamptz",
+	"time.Duration":   "int8",
+	"[]byte":          "bytea",
+	"[]string":        "_text",
+	"[]int":           "_int8",
+	"[]int64":         "_int8",
+	"[]int32":         "_int4",
+	"[]float64":       "_float8",
+	"[]float32":       "_float4",
+	"interface{}":     "jsonb",
+	"any":             "jsonb",
+	"json.RawMessage": "jsonb",
+	"uuid.UUID":       "uuid",
+	"decimal.Decimal": "numeric",
+	"net.IP":          "inet",
+}
+
+// reverseNullTypes are the database/sql null wrappers, nullable by type.
+var reverseNullTypes = map[string]string{
+	"sql.NullString":  "text",
+	"sql.NullInt64":   "int8",
+	"sql.NullInt32":   "int4",
+	"sql.NullInt16":   "int2",
+	"sql.NullBool":    "bool",
+	"sql.NullFloat64": "float8",
+	"sql.NullTime":    "timestamptz",
+}
+
+// toSnakeCase is the reverse of toCamelCase, UserID becomes user_id.
+func toSnakeCase(in string) string {
+	runes := []rune(in)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// ParseModels reads the schema that tagged Go model structs describe, the
+// reverse of generation. Structs are models if they have a tableName field
+// or a field with a sql, pg or db tag. Tags are read as go-pg writes them,
+// e.g. `sql:"name,notnull"` or `pg:"name,pk,type:varchar(20)"`, untagged
+// fields are snake_cased. String types with constants are enums. Paths are
+// .go files or directories of them, tests left out.
+func ParseModels(paths []string, schema string) (*introspect.Schema, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{path}
+		if info.IsDir() {
+			if names, err = filepath.Glob(filepath.Join(path, "*.go")); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range names {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+	}
+
+	r := &reverser{
+		schema:  schema,
+		structs: make(map[string]*ast.StructType),
+		enums:   make(map[string][]string),
+	}
+	for _, f := range files {
+		r.collect(f)
+	}
+	return r.build(), nil
+}
+
+type reverser struct {
+	schema  string
+	names   []string
+	structs map[string]*ast.StructType
+	// enums are the labels of named string types, by Go type name
+	enums map[string][]string
+	used  map[string]bool
+}
+
+func (r *reverser) collect(f *ast.File) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				switch t := s.Type.(type) {
+				case *ast.StructType:
+					r.names = append(r.names, s.Name.Name)
+					r.structs[s.Name.Name] = t
+				case *ast.Ident:
+					if t.Name == "string" {
+						if _, ok := r.enums[s.Name.Name]; !ok {
+							r.enums[s.Name.Name] = nil
+						}
+					}
+				}
+			case *ast.ValueSpec:
+				// constants of a string type become its labels
+				ident, ok := s.Type.(*ast.Ident)
+				if gen.Tok != token.CONST || !ok {
+					continue
+				}
+				for _, value := range s.Values {
+					if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						if label, err := strconv.Unquote(lit.Value); err == nil {
+							r.enums[ident.Name] = append(r.enums[ident.Name], label)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// splitTag splits a tag value at commas outside of double quotes and
+// parentheses, such as those of type:numeric(12,2).
+func splitTag(tag string) []string {
+	var (
+		parts  []string
+		start  int
+		quoted bool
+		depth  int
+	)
+	for i, r := range tag {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '(' && !quoted:
+			depth++
+		case r == ')' && !quoted && depth > 0:
+			depth--
+		case r == ',' && !quoted && depth == 0:
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// modelTag returns the column tag of field, sql before pg before db.
+func modelTag(field *ast.Field) (string, bool) {
+	if field.Tag == nil {
+		return "", false
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return "", false
+	}
+	for _, key := range []string{"sql", "pg", "db"} {
+		if value, ok := reflect.StructTag(tag).Lookup(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (r *reverser) isModel(s *ast.StructType) bool {
+	for _, field := range s.Fields.List {
+		if _, ok := modelTag(field); ok {
+			return true
+		}
+	}
+	return hasTableName(s)
+}
+
+func hasTableName(s *ast.StructType) bool {
+	for _, field := range s.Fields.List {
+		for _, name := range field.Names {
+			if name.Name == "tableName" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *reverser) build() *introspect.Schema {
+	s := &introspect.Schema{
+		Version: introspect.SnapshotVersion,
+		Dialect: "postgres",
+		Schemas: []string{r.schema},
+		Tables:  make(introspect.DBTables),
+		Enums:   make(introspect.DBEnums),
+	}
+	r.used = make(map[string]bool)
+	// structs models embed aren't tables of their own
+	embedded := make(map[string]bool)
+	for _, st := range r.structs {
+		for _, field := range st.Fields.List {
+			if ident, ok := field.Type.(*ast.Ident); ok && len(field.Names) == 0 {
+				embedded[ident.Name] = true
+			}
+		}
+	}
+	for _, name := range r.names {
+		st := r.structs[name]
+		if !r.isModel(st) || embedded[name] && !hasTableName(st) {
+			continue
+		}
+		table := r.table(name, st)
+		if len(table) > 0 {
+			s.Tables[table[0].TableSchema+"."+table[0].TableName] = table
+		}
+	}
+	for goType := range r.used {
+		if labels := r.enums[goType]; len(labels) > 0 {
+			s.Enums[toSnakeCase(goType)] = labels
+		}
+	}
+	return s
+}
+
+// table reads the columns of model name.
+func (r *reverser) table(name string, st *ast.StructType) []introspect.DBColumn {
+	schema, table := r.schema, toSnakeCase(name)
+	var (
+		columns []introspect.DBColumn
+		hasPK   bool
+	)
+	for _, field := range r.fields(st) {
+		tag, tagged := modelTag(field)
+		opts := splitTag(tag)
+		// pg:"default:now()" has options only
+		if strings.Contains(opts[0], ":") {
+			opts = append([]string{""}, opts...)
+		}
+		if len(field.Names) == 1 && field.Names[0].Name == "tableName" {
+			if opts[0] != "" {
+				name := opts[0]
+				if i := strings.LastIndex(name, "."); i >= 0 {
+					schema, name = strings.Trim(name[:i], `"`), name[i+1:]
+				}
+				table = strings.Trim(name, `"`)
+			}
+			continue
+		}
+		if opts[0] == "-" || len(field.Names) == 0 || !ast.IsExported(field.Names[0].Name) {
+			continue
+		}
+		goType := exprString(field.Type)
+		col := introspect.DBColumn{
+			TableSchema: schema,
+			ColumnName:  opts[0],
+			IsNullable:  true,
+		}
+		// quoted as an SQL identifier
+		if name := col.ColumnName; len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+			col.ColumnName = strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+		}
+		if !tagged || col.ColumnName == "" {
+			col.ColumnName = toSnakeCase(field.Names[0].Name)
+		}
+		base := strings.TrimPrefix(goType, "*")
+		udt, ok := r.columnType(base)
+		if !ok {
+			// relations to other models aren't columns
+			report.Debug.Printf("reverse: skipping %s.%s of type %s", name, field.Names[0].Name, goType)
+			continue
+		}
+		_, nullType := reverseNullTypes[base]
+		col.UDTName = udt
+		col.DataType = udt
+		// values are not null unless they can hold nil or are null wrappers
+		col.IsNullable = strings.HasPrefix(goType, "*") || nullType || strings.HasPrefix(goType, "[]") || udt == "jsonb"
+		for _, opt := range opts[1:] {
+			switch {
+			case opt == "notnull":
+				col.IsNullable = false
+			case opt == "pk":
+				col.IsPrimaryKey = true
+			case strings.HasPrefix(opt, "type:"):
+				setColumnType(&col, strings.TrimPrefix(opt, "type:"))
+			case strings.HasPrefix(opt, "default:"):
+				def := strings.TrimPrefix(opt, "default:")
+				col.ColumnDefault = &def
+			}
+		}
+		if comment := strings.TrimSpace(field.Doc.Text() + field.Comment.Text()); comment != "" {
+			col.Comment = &comment
+		}
+		hasPK = hasPK || col.IsPrimaryKey
+		columns = append(columns, col)
+	}
+	for i := range columns {
+		columns[i].TableSchema, columns[i].TableName = schema, table
+		columns[i].OrdinalPosition = i + 1
+		// go-pg's convention without a pk option
+		if !hasPK && columns[i].ColumnName == "id" {
+			columns[i].IsPrimaryKey = true
+			columns[i].IsNullable = false
+		}
+	}
+	return columns
+}
+
+// fields returns the fields of st with those of embedded structs, such as a
+// shared Timestamps, in their place.
+func (r *reverser) fields(st *ast.StructType) []*ast.Field {
+	var fields []*ast.Field
+	for _, field := range st.Fields.List {
+		if ident, ok := field.Type.(*ast.Ident); ok && len(field.Names) == 0 && field.Tag == nil {
+			if embedded, ok := r.structs[ident.Name]; ok && embedded != st {
+				fields = append(fields, r.fields(embedded)...)
+				continue
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// columnType returns the column type of goType, false for other models.
+func (r *reverser) columnType(goType string) (string, bool) {
+	if udt, ok := reverseTypes[goType]; ok {
+		return udt, true
+	}
+	if udt, ok := reverseNullTypes[goType]; ok {
+		return udt, true
+	}
+	elem := strings.TrimPrefix(goType, "[]")
+	if len(r.enums[elem]) > 0 {
+		r.used[elem] = true
+		if elem != goType {
+			return "_" + toSnakeCase(elem), true
+		}
+		return toSnakeCase(elem), true
+	}
+	if _, ok := r.structs[strings.TrimPrefix(elem, "*")]; ok {
+		return "", false
+	}
+	if strings.HasPrefix(goType, "map[") {
+		return "jsonb", true
+	}
+	report.Debug.Printf("reverse: no column type for %s, using text", goType)
+	return "text", true
+}
+
+// setColumnType sets the type of col from a type written in DDL, such as
+// varchar(20) or double precision.
+func setColumnType(col *introspect.DBColumn, sqlType string) {
+	parsed, err := introspect.ParseDDL("CREATE TABLE t (c " + sqlType + ")")
+	if err != nil {
+		col.UDTName, col.DataType = sqlType, sqlType
+		return
+	}
+	c := parsed.Tables["public.t"][0]
+	col.UDTName, col.DataType = c.UDTName, c.DataType
+	col.CharacterMaximumLength, col.NumericPrecision, col.NumericScale = c.CharacterMaximumLength, c.NumericPrecision, c.NumericScale
+}
+
+// exprString renders a field type the way it's written.
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + exprString(t.Elt)
+	case *ast.MapType:
+		return "map[" + exprString(t.Key) + "]" + exprString(t.Value)
+	case *ast.InterfaceType:
+		return "interface{}"
+	}
+	return fmt.Sprintf("%T", expr)
+}
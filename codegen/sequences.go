@@ -0,0 +1,80 @@
+package codegen
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const (
+	sequenceSharedTpl = `
+// Querier runs the queries of Next helpers, *sql.DB and *sql.Tx are ones.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+`
+
+	sequenceTpl = `
+const (
+{{range .}}	{{.Const}} = {{printf "%q" .Sequence}}
+{{end}})
+`
+
+	nextvalTpl = `{{range .}}
+// {{.Func}} reads the next value of {{.Const}}, for code that needs
+// it before inserting.
+func {{.Func}}(ctx context.Context, db Querier) ({{.Type}}, error) {
+	var v {{.Type}}
+	err := db.QueryRowContext(ctx, "SELECT nextval($1)", {{.Const}}).Scan(&v)
+	return v, err
+}
+{{end}}`
+)
+
+var (
+	nextvalDefault  = regexp.MustCompile(`^nextval\('((?:[^']|'')+)'(?:::regclass)?\)$`)
+	identityDefault = regexp.MustCompile(`^generated (?:always|by default) as identity \(sequence name (.+)\)$`)
+)
+
+// sequenceName returns the sequence filling col, as nextval takes it: the one
+// its default calls, or the one an identity column names. Identity columns
+// of snapshots taken before their sequences were read have none.
+func sequenceName(col introspect.DBColumn) string {
+	if col.ColumnDefault == nil {
+		return ""
+	}
+	def := strings.TrimSpace(*col.ColumnDefault)
+	if m := nextvalDefault.FindStringSubmatch(def); m != nil {
+		return strings.ReplaceAll(m[1], "''", "'")
+	}
+	if m := identityDefault.FindStringSubmatch(def); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// SequenceField is a field filled from a sequence.
+type SequenceField struct {
+	Const    string
+	Func     string
+	Sequence string
+	Type     string
+}
+
+// sequenceFields returns the fields of model filled from sequences.
+func sequenceFields(model Model) []SequenceField {
+	var fields []SequenceField
+	for _, field := range model.Fields {
+		if field.Sequence == "" {
+			continue
+		}
+		fields = append(fields, SequenceField{
+			Const:    model.Name + field.Name + "Seq",
+			Func:     "Next" + model.Name + field.Name,
+			Sequence: field.Sequence,
+			Type:     strings.TrimPrefix(field.Type, "*"),
+		})
+	}
+	return fields
+}
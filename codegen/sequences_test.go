@@ -0,0 +1,30 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+func TestSequenceName(t *testing.T) {
+	tests := []struct {
+		def  string
+		want string
+	}{
+		{"nextval('users_id_seq'::regclass)", "users_id_seq"},
+		{"nextval('billing.\"Users_id_seq\"'::regclass)", `billing."Users_id_seq"`},
+		{"nextval('it''s_seq'::regclass)", "it's_seq"},
+		{"generated always as identity (sequence name public.user_ids)", "public.user_ids"},
+		{"generated by default as identity (sequence name billing.\"T_id_seq\")", `billing."T_id_seq"`},
+		// snapshots of older versions don't name the sequence
+		{"generated always as identity", ""},
+		{"now()", ""},
+	}
+	for _, tt := range tests {
+		def := tt.def
+		col := introspect.DBColumn{TableSchema: "public", TableName: "users", ColumnName: "id", ColumnDefault: &def}
+		if got := sequenceName(col); got != tt.want {
+			t.Errorf("sequenceName(%q) = %q, want %q", tt.def, got, tt.want)
+		}
+	}
+}
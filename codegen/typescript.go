@@ -0,0 +1,127 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const typescriptTpl = `{{range $i, $m := .}}{{if $i}}
+{{end}}export interface {{$m.Name}} {
+{{range $m.Fields}}  {{.Name}}: {{.Type}};
+{{end}}}
+{{end}}`
+
+type TSInterface struct {
+	Name   string
+	Fields []TSField
+}
+
+type TSField struct {
+	Name string
+	Type string
+}
+
+var tsTypes = map[string]string{
+	"bool":        "boolean",
+	"int2":        "number",
+	"int4":        "number",
+	"int8":        "number",
+	"float4":      "number",
+	"float8":      "number",
+	"numeric":     "string",
+	"varchar":     "string",
+	"text":        "string",
+	"bpchar":      "string",
+	"uuid":        "string",
+	"tsvector":    "string",
+	"tsquery":     "string",
+	"bytea":       "string",
+	"timestamp":   "Date",
+	"timestamptz": "Date",
+	"date":        "Date",
+	"json":        "unknown",
+	"jsonb":       "unknown",
+}
+
+// tsIdentifier matches the property names that need no quotes.
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsString quotes s as a TypeScript string literal.
+func tsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// tsType returns the type of col, enums as unions of their labels.
+func tsType(col introspect.DBColumn, enums introspect.DBEnums) (string, error) {
+	udt, isArray := elementType(col.UDTName)
+
+	t, ok := tsTypes[udt]
+	if labels, isEnum := enums[udt]; isEnum {
+		quoted := make([]string, len(labels))
+		for i, label := range labels {
+			quoted[i] = tsString(label)
+		}
+		t, ok = strings.Join(quoted, " | "), true
+		if len(labels) == 0 {
+			t = "never"
+		}
+		if isArray && len(labels) > 1 {
+			t = "(" + t + ")"
+		}
+	}
+	if !ok {
+		return "", fmt.Errorf("typescript type not detected for %s", col.UDTName)
+	}
+	if isArray {
+		t += "[]"
+	}
+	if col.IsNullable {
+		t += " | null"
+	}
+	return t, nil
+}
+
+// RenderTypeScript renders an interface per model. Property names are the
+// column names, matching the json tags of the generated DTOs, quoted where
+// they aren't identifiers.
+func RenderTypeScript(models []Model, enums introspect.DBEnums) ([]byte, error) {
+	interfaces := make([]TSInterface, 0, len(models))
+	for _, model := range models {
+		report.Steps.Add(1)
+		iface := TSInterface{Name: model.Name}
+		for _, field := range model.Fields {
+			t, err := tsType(field.Column, enums)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", model.Key(), field.Column.ColumnName, err)
+			}
+			name := field.Column.ColumnName
+			if !tsIdentifier.MatchString(name) {
+				name = tsString(name)
+			}
+			iface.Fields = append(iface.Fields, TSField{
+				Name: name,
+				Type: t,
+			})
+		}
+		interfaces = append(interfaces, iface)
+	}
+
+	tmpl, err := template.New("typescript").Parse(typescriptTpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, interfaces); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,42 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+func TestRenderTypeScript(t *testing.T) {
+	s, err := introspect.ParseDDL(`
+		CREATE TYPE mood AS ENUM ('sad', 'happy');
+		CREATE TABLE users (
+			id integer PRIMARY KEY,
+			"2fa_enabled" bool NOT NULL,
+			"full name" text,
+			"last-login" timestamp,
+			mood mood NOT NULL,
+			moods mood[]
+		);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := BuildModels(s.Tables, typemap.Fallback("string"))
+	NameModels(models, NamingOptions{Singular: true})
+	content, err := RenderTypeScript(models, s.Enums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `export interface User {
+  id: number;
+  "2fa_enabled": boolean;
+  "full name": string | null;
+  "last-login": Date | null;
+  mood: "sad" | "happy";
+  moods: ("sad" | "happy")[] | null;
+}
+`
+	if got := string(content); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,227 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+const (
+	updateSharedTpl = `
+// Execer runs the queries of Update methods, *sql.DB and *sql.Tx are ones.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ErrStaleVersion is returned by Update when the row was changed or deleted
+// since it was read.
+var ErrStaleVersion = errors.New("row was changed or deleted concurrently")
+
+// arrayArg passes a slice, or a pointer to one, as a Postgres array.
+type arrayArg struct{ v interface{} }
+
+func (a arrayArg) Value() (driver.Value, error) {
+	v := reflect.ValueOf(a.v)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	return pq.Array(v.Interface()).Value()
+}
+
+// jsonArg passes a value, or a pointer to one, as JSON.
+type jsonArg struct{ v interface{} }
+
+func (j jsonArg) Value() (driver.Value, error) {
+	v := reflect.ValueOf(j.v)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Slice) && v.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// vectorArg passes a []float32, or a pointer to one, as a pgvector vector.
+type vectorArg struct{ v interface{} }
+
+func (a vectorArg) Value() (driver.Value, error) {
+	var vec []float32
+	switch v := a.v.(type) {
+	case []float32:
+		vec = v
+	case *[]float32:
+		if v == nil {
+			return nil, nil
+		}
+		vec = *v
+	}
+	values := make([]string, len(vec))
+	for i, f := range vec {
+		values[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	return "[" + strings.Join(values, ",") + "]", nil
+}
+`
+
+	updateTpl = `
+{{if .Version}}// Update writes m to its row if the row's {{.Version}} is still the one read,
+// and returns ErrStaleVersion otherwise.
+func (m *{{.Name}}) Update(ctx context.Context, db Execer) error {
+	res, err := db.ExecContext(ctx, {{.Literal}}{{range .Args}}, {{.}}{{end}})
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStaleVersion
+	}
+	m.{{.Version}}++
+	return nil
+}
+{{else}}// Update writes m to its row.
+func (m *{{.Name}}) Update(ctx context.Context, db Execer) error {
+	_, err := db.ExecContext(ctx, {{.Literal}}{{range .Args}}, {{.}}{{end}})
+	return err
+}
+{{end}}`
+)
+
+// versionTypes are the Go types a version column may have.
+var versionTypes = map[string]bool{"int": true, "int16": true, "int32": true, "int64": true}
+
+// MarkVersion marks the first not null integer column of each model in
+// columns, "column" or "table.column", as its optimistic locking version.
+func MarkVersion(models []Model, columns []string) {
+	for i, model := range models {
+		for j, field := range model.Fields {
+			if !matchColumn(columns, model.TableName, field.Column.ColumnName) || field.Column.IsPrimaryKey {
+				continue
+			}
+			if !versionTypes[field.Type] {
+				report.Debug.Printf("column %s.%s: %s is not a not null integer, not using it as version", model.Key(), field.Column.ColumnName, field.Type)
+				continue
+			}
+			models[i].Fields[j].Version = true
+			break
+		}
+	}
+}
+
+// Version returns the optimistic locking version field of the model, if it
+// has one.
+func (m Model) Version() *Field {
+	for i := range m.Fields {
+		if m.Fields[i].Version {
+			return &m.Fields[i]
+		}
+	}
+	return nil
+}
+
+// UpdateQuery is what the Update method of a model runs: the query and the
+// arguments for its placeholders.
+type UpdateQuery struct {
+	Name    string
+	Query   string
+	Args    []string
+	Version string
+}
+
+// Literal is the query as a Go string literal.
+func (q UpdateQuery) Literal() string {
+	return stringLiteral(q.Query)
+}
+
+// stringLiteral returns s as a Go string literal, raw unless it has
+// backquotes.
+func stringLiteral(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// updateArg returns the argument passing field to ExecContext, wrapped where
+// drivers can't take the Go type the field has by default: slices of array
+// columns, vectors and JSON values that aren't a type of their own.
+func updateArg(field Field) string {
+	arg := "m." + field.Name
+	t := strings.TrimPrefix(field.Type, "*")
+	switch {
+	case field.Column.UDTName == "vector" && t == "[]float32":
+		return "vectorArg{" + arg + "}"
+	case strings.HasPrefix(field.Column.UDTName, "_") && strings.HasPrefix(t, "[]"):
+		return "arrayArg{" + arg + "}"
+	case (field.Column.UDTName == "json" || field.Column.UDTName == "jsonb") && (t == "interface{}" || t == "json.RawMessage"):
+		return "jsonArg{" + arg + "}"
+	}
+	return arg
+}
+
+// generatedAlways reports whether the database computes col itself, as for
+// GENERATED ALWAYS identity and generated columns.
+func generatedAlways(col introspect.DBColumn) bool {
+	return col.ColumnDefault != nil && strings.HasPrefix(strings.ToLower(*col.ColumnDefault), "generated always")
+}
+
+// updateQuery returns the UPDATE statement of model by primary key, with the
+// version checked and bumped, and false for models it can't update.
+func updateQuery(model Model) (UpdateQuery, bool) {
+	var (
+		set, where []string
+		args, keys []string
+		version    = model.Version()
+	)
+	for _, field := range model.Fields {
+		column := quoteIdent(field.Column.ColumnName)
+		switch {
+		case field.DBOnly && !field.Column.IsPrimaryKey:
+			// text search values are left to the database, e.g. to a trigger
+			continue
+		case field.Column.IsPrimaryKey:
+			keys = append(keys, field.Name)
+			where = append(where, column)
+		case generatedAlways(field.Column):
+			// Postgres rejects values for identity and generated columns
+			continue
+		case version != nil && field.Name == version.Name:
+			set = append(set, fmt.Sprintf("%s = %s + 1", column, column))
+		default:
+			args = append(args, updateArg(field))
+			set = append(set, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	if len(keys) == 0 || len(set) == 0 {
+		report.Debug.Printf("table %s: no primary key or nothing to update, not generating Update", model.Key())
+		return UpdateQuery{}, false
+	}
+	for i, column := range where {
+		args = append(args, "m."+keys[i])
+		where[i] = fmt.Sprintf("%s = $%d", column, len(args))
+	}
+	q := UpdateQuery{Name: model.Name}
+	if version != nil {
+		args = append(args, "m."+version.Name)
+		where = append(where, fmt.Sprintf("%s = $%d", quoteIdent(version.Column.ColumnName), len(args)))
+		q.Version = version.Name
+	}
+
+	table := quoteIdent(model.TableName)
+	if model.Schema != "" && model.Schema != "public" {
+		table = quoteIdent(model.Schema) + "." + table
+	}
+	q.Query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), strings.Join(where, " AND "))
+	q.Args = args
+	return q, true
+}
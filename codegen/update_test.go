@@ -0,0 +1,46 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateHelpers(t *testing.T) {
+	models, enums := testModels(t, `
+		CREATE TYPE mood AS ENUM ('sad', 'happy');
+		CREATE TABLE users (
+			id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			seq int GENERATED ALWAYS AS IDENTITY,
+			ticket int GENERATED BY DEFAULT AS IDENTITY,
+			name text NOT NULL,
+			name_length int GENERATED ALWAYS AS (length(name)) STORED,
+			tags text[],
+			mood mood,
+			settings jsonb,
+			search tsvector,
+			version int NOT NULL DEFAULT 1
+		);`)
+	MarkVersion(models, []string{"version"})
+	src := renderTestGo(t, models, enums, GoOptions{UpdateHelpers: true})
+
+	want := `UPDATE "users" SET "ticket" = $1, "name" = $2, "tags" = $3, "mood" = $4, "settings" = $5, "version" = "version" + 1 WHERE "id" = $6 AND "version" = $7`
+	if !strings.Contains(src, want) {
+		t.Errorf("Update doesn't run %q:\n%s", want, src)
+	}
+	for _, arg := range []string{"arrayArg{m.Tags}", "jsonArg{m.Settings}"} {
+		if !strings.Contains(src, arg) {
+			t.Errorf("Update doesn't pass %s:\n%s", arg, src)
+		}
+	}
+}
+
+func TestUpdateQueryNeedsAPrimaryKey(t *testing.T) {
+	models, _ := testModels(t, `
+		CREATE TABLE events (name text);
+		CREATE TABLE counters (id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY, total int GENERATED ALWAYS AS (id * 2) STORED);`)
+	for _, model := range models {
+		if q, ok := updateQuery(model); ok {
+			t.Errorf("table %s: got Update query %q, want none", model.Key(), q.Query)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/term"
+
+	"github.com/asyndrige/postgres-model-generator/codegen"
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+// Command is a subcommand. Run gets the args following the command name and
+// parses its own flags, ctx is canceled on interrupt. Flags registers the flags of commands that read the
+// config, besides -c, and is nil for the others.
+type Command struct {
+	Name    string
+	Summary string
+	Flags   func(*Config, *flag.FlagSet)
+	Run     func(ctx context.Context, name string, args []string) error
+	Hidden  bool
+}
+
+// commands are listed in usage in this order, the first one is the default.
+var commands = []*Command{
+	{Name: "generate", Summary: "write the generated files to the output directory", Flags: generateFlags, Run: runGenerate},
+	{Name: "diff", Summary: "print a diff of what generate would change, or with -against of the schema since a snapshot; exit with status 2 if anything would change", Flags: diffFlags, Run: runDiff},
+	{Name: "list", Summary: "list the tables of the configured schemas and whether the filters include them", Flags: (*Config).RegisterFlags, Run: runList},
+	{Name: "snapshot", Summary: "write the introspected schema to a JSON or YAML snapshot, for offline generation and diffing", Flags: snapshotFlags, Run: runSnapshot},
+	{Name: "check", Summary: "verify the config, the connection and that every table can be generated", Flags: outputFlags, Run: runCheck},
+	{Name: "reverse", Summary: "print CREATE TABLE statements for tagged Go model structs, the reverse of generate", Run: runReverse},
+	{Name: "gogenerate", Summary: "generate from a //go:generate directive, with all settings read from the config file (default ./config)", Run: runGoGenerate},
+	{Name: "version", Summary: "print version and build information", Run: runVersion},
+}
+
+func outputFlags(cfg *Config, fs *flag.FlagSet) {
+	cfg.RegisterFlags(fs)
+	cfg.RegisterOutputFlags(fs)
+}
+
+func generateFlags(cfg *Config, fs *flag.FlagSet) {
+	outputFlags(cfg, fs)
+	fs.BoolVar(&cfg.Force, "force", cfg.Force, "overwrite existing files even if they don't carry the generated-code header")
+	fs.BoolVar(&cfg.Output.Incremental, "incremental", cfg.Output.Incremental, "rewrite only the files of tables that changed since the last -incremental run, with -sf or per-model pack files")
+	fs.BoolVar(&cfg.Watch, "watch", cfg.Watch, "keep running and regenerate whenever the schema changes")
+	fs.DurationVar(&cfg.WatchInterval, "watch-interval", cfg.WatchInterval, "how often -watch checks the schema for changes")
+}
+
+func diffFlags(cfg *Config, fs *flag.FlagSet) {
+	outputFlags(cfg, fs)
+	fs.StringVar(&cfg.DiffAgainst, "against", cfg.DiffAgainst, "snapshot to compare the schema with, listing added, removed and changed tables and columns instead of file changes")
+	fs.BoolVar(&cfg.DiffJSON, "json", cfg.DiffJSON, "print the -against schema diff as JSON")
+	fs.StringVar(&cfg.MigrationDir, "migration-dir", cfg.MigrationDir, "write the -against schema diff as up and down migration stubs (golang-migrate naming) to this directory")
+}
+
+func snapshotFlags(cfg *Config, fs *flag.FlagSet) {
+	cfg.RegisterFlags(fs)
+	fs.StringVar(&cfg.SnapshotFile, "o", cfg.SnapshotFile, "snapshot file, YAML if it ends in .yaml or .yml, JSON otherwise; stdout (JSON) if empty")
+}
+
+// errChanged makes the process exit with status 2, a plain error exits with 1.
+var errChanged = errors.New("generated files are out of date")
+
+func findCommand(name string) (*Command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+func usage(w io.Writer, name string) {
+	fmt.Fprintf(w, "usage: %s [command] [flags]\n\ncommands:\n", name)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		if cmd.Hidden {
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name, cmd.Summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nrun '%s <command> -h' for the flags of a command\n", name)
+}
+
+// loadConfig is LoadConfig plus the setup shared by all commands.
+func loadConfig(name string, args []string, register func(*Config, *flag.FlagSet)) (*Config, error) {
+	cfg, err := LoadConfig(name, args, register)
+	if err != nil {
+		return nil, err
+	}
+	applyConfig(cfg)
+	if cfg.Verbose {
+		report.Debug.SetOutput(os.Stderr)
+	} else if !cfg.Quiet && term.IsTerminal(int(os.Stderr.Fd())) {
+		report.Steps.SetOutput(os.Stderr)
+	}
+	return cfg, nil
+}
+
+// applyConfig sets the package level settings of codegen from cfg and fills
+// in the defaults that depend on the environment, for every command that
+// generates code.
+func applyConfig(cfg *Config) {
+	codegen.Workers = cfg.Jobs
+	codegen.Initialisms = cfg.Initialisms
+	codegen.LeadingDigits = cfg.LeadingDigits
+	if (cfg.Go.SchemaPackages || len(cfg.Go.Packages) > 0 || len(cfg.PackagePrefixes) > 0) && cfg.Go.ImportPath == "" {
+		cfg.Go.ImportPath = moduleImportPath(cfg.Output.Dir)
+	}
+}
+
+// moduleImportPath returns the import path of dir in the module of the
+// closest go.mod above it, or "" if there is none.
+func moduleImportPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for root := dir; ; root = filepath.Dir(root) {
+		data, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			module := modfile.ModulePath(data)
+			if module == "" {
+				return ""
+			}
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return ""
+			}
+			return path.Join(module, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// connect opens the database cfg points to.
+func connect(ctx context.Context, cfg *Config) (*introspect.Introspector, error) {
+	connStr, err := cfg.Connection.ConnString()
+	if err != nil {
+		return nil, err
+	}
+	dialect := introspect.Dialects[cfg.Connection.Dialect]
+	if cfg.Connection.Catalog {
+		dialect = introspect.PostgresCatalog
+	}
+	return introspect.Open(ctx, dialect, cfg.Connection.SQLDriver(), connStr)
+}
+
+// openSource reads the -ddl files, -migrations or -from-snapshot if given and
+// connects to the database otherwise, going through the -cache if set.
+func openSource(ctx context.Context, cfg *Config) (introspect.Source, error) {
+	var (
+		schema *introspect.Schema
+		err    error
+	)
+	switch {
+	case len(cfg.DDL) > 0:
+		schema, err = introspect.ParseDDLFiles(cfg.DDL)
+	case cfg.Migrations != "":
+		schema, err = introspect.ParseMigrations(cfg.Migrations)
+	case cfg.FromSnapshot != "":
+		schema, err = introspect.LoadSnapshot(cfg.FromSnapshot)
+	default:
+		db, err := connect(ctx, cfg)
+		if err != nil || cfg.Cache == "" {
+			return db, err
+		}
+		return cachedSource(ctx, cfg, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schema.Source(), nil
+}
+
+// writeFiles writes files below dir and runs the file hooks on them. With
+// -incremental, files of unchanged tables are left out.
+func writeFiles(cfg *Config, dir string, files []codegen.OutputFile, info codegen.GenerationInfo) error {
+	if cfg.Output.Incremental {
+		files = skipUnchanged(cfg, dir, files, info)
+	}
+	if err := codegen.WriteFiles(dir, files, info, cfg.Force); err != nil {
+		return err
+	}
+	return finishFiles(cfg, dir, files, info)
+}
+
+// streamFiles is writeFiles for go files a file per model, which are staged
+// as they're rendered instead of all being held in memory first and only
+// replace the files below dir once all rendered. Files of unchanged tables
+// aren't even rendered.
+func streamFiles(cfg *Config, dir string, s *schemaModels) (int, error) {
+	files, err := codegen.GoFiles(s.models, s.enums, cfg.Go)
+	if err != nil {
+		return 0, err
+	}
+	count := len(files)
+	if cfg.Output.Incremental {
+		files = skipUnchanged(cfg, dir, files, s.info)
+	}
+	if err := codegen.CheckFiles(dir, files, s.info, cfg.Force); err != nil {
+		return 0, err
+	}
+	step := time.Now()
+	report.Steps.Start("writing models", len(files))
+	staging := codegen.NewStaging(dir, s.info, cfg.Force)
+	err = codegen.StreamGo(files, s.models, s.enums, cfg.Go, staging.Write)
+	if err == nil {
+		err = staging.Commit()
+	}
+	if err != nil {
+		staging.Discard()
+		return 0, err
+	}
+	report.Steps.Finish()
+	report.Debug.Printf("rendered and wrote %d go files in %s", len(files), time.Since(step))
+	return count, finishFiles(cfg, dir, files, s.info)
+}
+
+// finishFiles saves the -incremental state and runs the file hooks on the
+// files just written.
+func finishFiles(cfg *Config, dir string, files []codegen.OutputFile, info codegen.GenerationInfo) error {
+	if cfg.Output.Incremental {
+		if err := saveState(cfg, dir, info); err != nil {
+			return err
+		}
+	}
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Join(dir, file.Name)
+	}
+	return codegen.RunFileHooks(cfg.Hooks.hooks(), paths)
+}
+
+// generateFiles generates the files of cfg and writes them below dir,
+// returning how many there are. Go files written a file per model are
+// streamed.
+func generateFiles(ctx context.Context, cfg *Config, dir string) (int, error) {
+	s, err := loadModels(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+	if cfg.Format == "go" && cfg.Go.SeparateFiles {
+		return streamFiles(cfg, dir, s)
+	}
+	files, err := s.render(cfg)
+	if err != nil {
+		return 0, err
+	}
+	return len(files), writeFiles(cfg, dir, files, s.info)
+}
+
+func runGenerate(ctx context.Context, name string, args []string) error {
+	cfg, err := loadConfig(name, args, generateFlags)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(cfg.Output.Dir)
+	if err != nil {
+		return err
+	}
+	n, err := generateFiles(ctx, cfg, dir)
+	if err != nil {
+		return err
+	}
+	if !cfg.Quiet {
+		fmt.Printf("wrote %d files to %s\n", n, dir)
+	}
+	if !cfg.Watch {
+		return nil
+	}
+
+	return watch(ctx, cfg, cfg.WatchInterval, func() error {
+		n, err := generateFiles(ctx, cfg, dir)
+		if err != nil {
+			return err
+		}
+		if !cfg.Quiet {
+			fmt.Printf("schema changed, wrote %d files to %s\n", n, dir)
+		}
+		return nil
+	})
+}
+
+func runDiff(ctx context.Context, name string, args []string) error {
+	cfg, err := loadConfig(name, args, diffFlags)
+	if err != nil {
+		return err
+	}
+	if cfg.DiffAgainst != "" {
+		return diffSchema(ctx, cfg)
+	}
+	files, info, err := generate(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.Quiet {
+		out = ioutil.Discard
+	}
+	changed, err := codegen.PreviewFiles(out, cfg.Output.Dir, files, info)
+	if err == nil && changed {
+		err = errChanged
+	}
+	return err
+}
+
+// diffSchema compares the schema with the -against snapshot, both limited
+// to the configured schemas.
+func diffSchema(ctx context.Context, cfg *Config) error {
+	old, err := introspect.LoadSnapshot(cfg.DiffAgainst)
+	if err != nil {
+		return err
+	}
+	if old, err = introspect.Snapshot(ctx, old.Source(), old.Dialect, cfg.Schemas); err != nil {
+		return err
+	}
+	db, err := openSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	current, err := introspect.Snapshot(ctx, db, cfg.Connection.Dialect, cfg.Schemas)
+	if err != nil {
+		return err
+	}
+
+	diff := introspect.DiffSchemas(old, current)
+	switch {
+	case cfg.Quiet:
+	case cfg.DiffJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(diff)
+	default:
+		err = diff.WriteText(os.Stdout)
+	}
+	if err == nil && cfg.MigrationDir != "" && !diff.Empty() {
+		err = writeMigration(cfg, diff)
+	}
+	if err == nil && !diff.Empty() {
+		err = errChanged
+	}
+	return err
+}
+
+// writeMigration writes the statements of diff to a new up and down
+// migration in cfg.MigrationDir.
+func writeMigration(cfg *Config, diff introspect.SchemaDiff) error {
+	if err := os.MkdirAll(cfg.MigrationDir, 0755); err != nil {
+		return err
+	}
+	up, down := diff.MigrationSQL()
+	header := fmt.Sprintf("-- Generated by %s from the changes since %s, review before applying.\n\n", filepath.Base(os.Args[0]), cfg.DiffAgainst)
+	base := filepath.Join(cfg.MigrationDir, time.Now().UTC().Format("20060102150405")+"_schema_changes")
+	for path, stmts := range map[string][]string{base + ".up.sql": up, base + ".down.sql": down} {
+		content := header + strings.Join(stmts, "\n\n") + "\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		if !cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "wrote %s\n", path)
+		}
+	}
+	return nil
+}
+
+func runList(ctx context.Context, name string, args []string) error {
+	cfg, err := loadConfig(name, args, (*Config).RegisterFlags)
+	if err != nil {
+		return err
+	}
+	db, err := openSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tables, err := db.Tables(ctx, cfg.Schemas)
+	if err != nil {
+		return err
+	}
+	comments, err := db.TableComments(ctx, cfg.Schemas)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tables))
+	for key := range tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCHEMA\tTABLE\tCOLUMNS\tINCLUDED")
+	for _, key := range keys {
+		columns := tables[key]
+		schema, table := columns[0].TableSchema, columns[0].TableName
+		included := "no"
+		switch {
+		case introspect.Marked(comments[key], cfg.NoGenMarker):
+			included = "no, " + cfg.NoGenMarker
+		case introspect.TableIncluded(schema, table, cfg.IncludeTables, cfg.ExcludeTables):
+			included = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", schema, table, len(columns), included)
+	}
+	return tw.Flush()
+}
+
+func runSnapshot(ctx context.Context, name string, args []string) error {
+	cfg, err := loadConfig(name, args, snapshotFlags)
+	if err != nil {
+		return err
+	}
+	db, err := openSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	schema, err := introspect.Snapshot(ctx, db, cfg.Connection.Dialect, cfg.Schemas)
+	if err != nil {
+		return err
+	}
+
+	if cfg.SnapshotFile == "" {
+		return introspect.WriteSnapshot(os.Stdout, schema, "json")
+	}
+	format := "json"
+	if ext := filepath.Ext(cfg.SnapshotFile); ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+	var buf bytes.Buffer
+	if err := introspect.WriteSnapshot(&buf, schema, format); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(cfg.SnapshotFile, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if !cfg.Quiet {
+		fmt.Printf("wrote a snapshot of %d tables to %s\n", len(schema.Tables), cfg.SnapshotFile)
+	}
+	return nil
+}
+
+func runCheck(ctx context.Context, name string, args []string) error {
+	cfg, err := loadConfig(name, args, outputFlags)
+	if err != nil {
+		return err
+	}
+	files, _, err := generate(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if !cfg.Quiet {
+		fmt.Printf("ok, %d %s files would be generated\n", len(files), cfg.Format)
+	}
+	return nil
+}
+
+// runGoGenerate takes no flags so that the config file next to the directive
+// is the single source of settings, and prints nothing unless it fails.
+func runGoGenerate(ctx context.Context, name string, args []string) error {
+	if os.Getenv("GOPACKAGE") == "" {
+		return fmt.Errorf("%s is meant to be run by go generate, use generate instead", name)
+	}
+	configPath := "config"
+	switch len(args) {
+	case 0:
+	case 1:
+		configPath = args[0]
+	default:
+		return fmt.Errorf("usage: %s [config file]", name)
+	}
+
+	cfg, err := LoadConfig(name, []string{"-c", configPath}, func(*Config, *flag.FlagSet) {})
+	if err != nil {
+		return err
+	}
+	applyConfig(cfg)
+	_, err = generateFiles(ctx, cfg, cfg.Output.Dir)
+	return err
+}
+
+// runReverse takes no config, the models are all it reads.
+func runReverse(ctx context.Context, name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	schema := fs.String("schema", "public", "schema of models whose tableName doesn't name one")
+	out := fs.String("o", "", "write the DDL to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] <go files or package directories>\n", name)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no models given")
+	}
+	models, err := codegen.ParseModels(fs.Args(), *schema)
+	if err != nil {
+		return err
+	}
+	if len(models.Tables) == 0 {
+		return errors.New("no model structs found")
+	}
+	ddl := models.CreateSQL()
+	if *out == "" {
+		_, err = io.WriteString(os.Stdout, ddl)
+		return err
+	}
+	return ioutil.WriteFile(*out, []byte(ddl), 0644)
+}
+
+func runVersion(ctx context.Context, name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Parse(args)
+	fmt.Print(versionInfo())
+	return nil
+}
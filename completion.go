@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const bashCompletionTpl = `# bash completion for {{.Prog}}, load it with:
+#   source <({{.Prog}} completion bash)
+_{{.Func}}() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	local cmd=generate first=1
+	case "${COMP_WORDS[1]}" in
+{{- range .Commands}}
+	{{.Name}}) cmd={{.Name}} first=2 ;;
+{{- end}}
+	esac
+
+	if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+		COMPREPLY=($(compgen -W "{{range $i, $c := .Commands}}{{if $i}} {{end}}{{$c.Name}}{{end}}" -- "$cur"))
+		return
+	fi
+
+	case "$prev" in
+{{- range .ArgFlags}}
+	-{{.Name}})
+{{- if .Tables}}
+		COMPREPLY=($(compgen -W "$({{$.Prog}} __complete tables "${COMP_WORDS[@]:first:COMP_CWORD-first-1}" 2>/dev/null)" -- "$cur"))
+{{- else if .Files}}
+		COMPREPLY=($(compgen -f -- "$cur"))
+{{- else if .Values}}
+		COMPREPLY=($(compgen -W "{{join .Values " "}}" -- "$cur"))
+{{- end}}
+		return ;;
+{{- end}}
+	esac
+
+	local flags
+	case "$cmd" in
+{{- range .Commands}}
+	{{.Name}}) flags="{{range $i, $f := .Flags}}{{if $i}} {{end}}-{{$f.Name}}{{end}}{{join .Args " "}}" ;;
+{{- end}}
+	esac
+	COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+}
+complete -F _{{.Func}} {{.Prog}}
+`
+
+const zshCompletionTpl = `#compdef {{.Prog}}
+# zsh completion for {{.Prog}}, load it with:
+#   source <({{.Prog}} completion zsh)
+autoload -U +X bashcompinit && bashcompinit
+` + bashCompletionTpl
+
+const fishCompletionTpl = `# fish completion for {{.Prog}}, load it with:
+#   {{.Prog}} completion fish | source
+complete -c {{.Prog}} -f
+{{- range .Commands}}
+complete -c {{$.Prog}} -n __fish_use_subcommand -a {{.Name}} -d {{quote .Summary}}
+{{- end}}
+{{- range .Commands}}{{$cmd := .}}
+{{- if .Args}}
+complete -c {{$.Prog}} -n {{condition $cmd}} -a {{quote (join .Args " ")}}
+{{- end}}
+{{- range .Flags}}
+complete -c {{$.Prog}} -n {{condition $cmd}} -o {{.Name}} -d {{quote .Usage}}
+{{- if .Tables}} -x -a '({{$.Prog}} __complete tables (commandline -opc)[2..-1])'
+{{- else if .Files}} -r -F
+{{- else if .Values}} -x -a {{quote (join .Values " ")}}
+{{- else if not .Bool}} -x
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+// completionValues are the choices of flags with a fixed set of values.
+var completionValues = map[string][]string{
+	"format":            {"go", "proto", "graphql", "ts", "jsonschema", "avro", "erd", "markdown", "pack"},
+	"erd":               {"mermaid", "dot"},
+	"dialect":           {"postgres", "cockroach", "mysql", "sqlite", "mssql"},
+	"driver":            {"pq", "pgx", "mysql", "sqlite", "sqlserver"},
+	"file-naming":       {"table", "singular"},
+	"schema-collisions": {"prefix", "suffix"},
+	"leading-digits":    {"spell", "prefix"},
+	"enum-style":        {"prefixed", "caps", "camel"},
+	"field-order":       {"ordinal", "alphabetical", "pk-first", "grouped"},
+	"ssl":               {"disable", "allow", "prefer", "require", "verify-ca", "verify-full"},
+}
+
+// completionFiles are the flags taking a path.
+var completionFiles = map[string]bool{
+	"c": true, "o": true, "templates": true, "ddl": true, "migrations": true, "from-snapshot": true, "cache": true, "password-file": true, "sslrootcert": true, "sslcert": true, "sslkey": true,
+}
+
+// completionTables are the flags completed with live table names.
+var completionTables = map[string]bool{"include": true, "exclude": true}
+
+type CompletionFlag struct {
+	Name   string
+	Usage  string
+	Bool   bool
+	Files  bool
+	Tables bool
+	Values []string
+}
+
+// CompletionCommand is a command as the completion scripts see it, Args are
+// the choices of its positional argument.
+type CompletionCommand struct {
+	Name    string
+	Summary string
+	Flags   []CompletionFlag
+	Args    []string
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func init() {
+	commands = append(commands,
+		&Command{Name: "completion", Summary: "print the bash, zsh or fish completion script", Run: runCompletion},
+		&Command{Name: "__complete", Run: runComplete, Hidden: true},
+	)
+}
+
+func runCompletion(ctx context.Context, name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Parse(args)
+
+	var tpl string
+	switch shell := fs.Arg(0); shell {
+	case "bash":
+		tpl = bashCompletionTpl
+	case "zsh":
+		tpl = zshCompletionTpl
+	case "fish":
+		tpl = fishCompletionTpl
+	case "":
+		return fmt.Errorf("usage: %s bash|zsh|fish", name)
+	default:
+		return fmt.Errorf("unsupported shell %q, use bash, zsh or fish", shell)
+	}
+
+	prog := strings.Fields(name)[0]
+	data := struct {
+		Prog     string
+		Func     string
+		Commands []CompletionCommand
+		ArgFlags []CompletionFlag
+	}{
+		Prog: prog,
+		Func: regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(prog, "_"),
+	}
+
+	// flags with the same name mean the same in all commands
+	argFlags := make(map[string]CompletionFlag)
+	for _, cmd := range commands {
+		if cmd.Hidden {
+			continue
+		}
+		c := CompletionCommand{Name: cmd.Name, Summary: cmd.Summary, Flags: completionFlags(cmd)}
+		if cmd.Name == "completion" {
+			c.Args = completionShells
+		}
+		data.Commands = append(data.Commands, c)
+		for _, f := range c.Flags {
+			if !f.Bool {
+				argFlags[f.Name] = f
+			}
+		}
+	}
+	for _, f := range argFlags {
+		data.ArgFlags = append(data.ArgFlags, f)
+	}
+	sort.Slice(data.ArgFlags, func(i, j int) bool {
+		return data.ArgFlags[i].Name < data.ArgFlags[j].Name
+	})
+
+	t := template.Must(template.New("completion").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"quote": func(s string) string {
+			return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+		},
+		// flags of the default command are offered before any command too
+		"condition": func(cmd CompletionCommand) string {
+			if cmd.Name == commands[0].Name {
+				return "'__fish_use_subcommand; or __fish_seen_subcommand_from " + cmd.Name + "'"
+			}
+			return "'__fish_seen_subcommand_from " + cmd.Name + "'"
+		},
+	}).Parse(tpl))
+	return t.Execute(os.Stdout, data)
+}
+
+func completionFlags(cmd *Command) []CompletionFlag {
+	if cmd.Flags == nil {
+		return nil
+	}
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.String("c", "config", "path to config file")
+	cmd.Flags(DefaultConfig(), fs)
+
+	var flags []CompletionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, CompletionFlag{
+			Name:   f.Name,
+			Usage:  f.Usage,
+			Bool:   ok && b.IsBoolFlag(),
+			Files:  completionFiles[f.Name],
+			Tables: completionTables[f.Name],
+			Values: completionValues[f.Name],
+		})
+	})
+	return flags
+}
+
+// runComplete serves the completion scripts, "__complete tables [flags]"
+// prints the table names of the database the flags connect to.
+func runComplete(ctx context.Context, name string, args []string) error {
+	if len(args) == 0 || args[0] != "tables" {
+		return fmt.Errorf("usage: %s tables [flags]", name)
+	}
+	args = args[1:]
+	if len(args) > 0 {
+		if _, ok := findCommand(args[0]); ok {
+			args = args[1:]
+		}
+	}
+	// drop a trailing flag that is still waiting for its value, and never
+	// prompt for a password from within the shell's completion
+	if len(args) > 0 && strings.HasPrefix(args[len(args)-1], "-") {
+		args = args[:len(args)-1]
+	}
+	var rest []string
+	for _, arg := range args {
+		if arg != "-W" && arg != "--W" {
+			rest = append(rest, arg)
+		}
+	}
+
+	cfg, err := LoadConfig(name, rest, outputFlags)
+	if err != nil {
+		return err
+	}
+	// a shell waiting on a slow database is worse than no completion
+	if cfg.Connection.ConnectTimeout == 0 || cfg.Connection.ConnectTimeout > 3*time.Second {
+		cfg.Connection.ConnectTimeout = 3 * time.Second
+	}
+	db, err := openSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tables, err := db.Tables(ctx, cfg.Schemas)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, columns := range tables {
+		schema, table := columns[0].TableSchema, columns[0].TableName
+		names = append(names, schema+"."+table)
+		if schema == "public" {
+			names = append(names, table)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Println(n)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/asyndrige/postgres-model-generator/codegen"
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+)
+
+// stateFile keeps, in the output directory, the schema and settings the files
+// there were last generated from by -incremental.
+const stateFile = ".pmg-state.json"
+
+type generationState struct {
+	Settings string             `json:"settings"`
+	Schema   *introspect.Schema `json:"schema"`
+}
+
+// settingsHash digests every setting, besides the schema, that generated
+// files depend on, including the files of a template pack and the hook
+// commands, which may rewrite models and files.
+func settingsHash(cfg *Config) string {
+	data, _ := json.Marshal(struct {
+		Version          string
+		Format           string
+		ERDStyle         string
+		Types            map[string]string
+		JSONTypes        map[string]map[string]string
+		NullTime         string
+		Initialisms      []string
+		SingularNames    bool
+		StripPrefixes    []string
+		StripSuffixes    []string
+		PackagePrefixes  []string
+		TableNames       map[string]string
+		SchemaCollisions string
+		LeadingDigits    string
+		EnumStyle        string
+		EnumNames        map[string]string
+		FieldOrder       string
+		FieldNames       map[string]string
+		SoftDelete       []string
+		VersionColumns   []string
+		RelationNames    map[string]string
+		HasManyFields    []string
+		Go               codegen.GoOptions
+		Templates        map[string][]byte
+		Hooks            HooksConfig
+	}{buildVersion(), cfg.Format, cfg.ERDStyle, cfg.Types, cfg.JSONTypes, cfg.NullTime, cfg.Initialisms, cfg.SingularNames, cfg.StripPrefixes, cfg.StripSuffixes, cfg.PackagePrefixes, cfg.TableNames, cfg.SchemaCollisions, cfg.LeadingDigits, cfg.EnumStyle, cfg.EnumNames, cfg.FieldOrder, cfg.FieldNames, cfg.SoftDelete, cfg.VersionColumns, cfg.RelationNames, cfg.HasManyFields, cfg.Go, templateFiles(cfg.Templates), cfg.Hooks})
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func templateFiles(dir string) map[string][]byte {
+	if dir == "" {
+		return nil
+	}
+	files := make(map[string][]byte)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files[path], _ = ioutil.ReadFile(path)
+		}
+		return nil
+	})
+	return files
+}
+
+// skipUnchanged drops the per-model files of tables that didn't change since
+// the files below dir were generated, as long as they exist and the settings
+// are the same. Other files are written as usual, WriteFiles leaves them
+// alone if their content is the same.
+func skipUnchanged(cfg *Config, dir string, files []codegen.OutputFile, info codegen.GenerationInfo) []codegen.OutputFile {
+	data, err := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	if err != nil {
+		return files
+	}
+	var state generationState
+	if err := json.Unmarshal(data, &state); err != nil || state.Schema == nil || info.Source == nil {
+		report.Debug.Printf("ignoring unreadable %s", stateFile)
+		return files
+	}
+	if state.Settings != settingsHash(cfg) {
+		report.Debug.Printf("settings changed, regenerating all files")
+		return files
+	}
+
+	changed := introspect.ChangedTables(state.Schema, info.Source)
+	kept := files[:0:0]
+	for _, file := range files {
+		if file.Table != "" && !changed[file.Table] {
+			if _, err := os.Stat(filepath.Join(dir, file.Name)); err == nil {
+				report.Debug.Printf("table %s unchanged, skipping %s", file.Table, file.Name)
+				continue
+			}
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// saveState records what the files below dir were generated from.
+func saveState(cfg *Config, dir string, info codegen.GenerationInfo) error {
+	data, err := json.Marshal(generationState{Settings: settingsHash(cfg), Schema: info.Source})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, stateFile), data, 0644)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/asyndrige/postgres-model-generator/codegen"
+)
+
+const initConfigTpl = `# Written by {{.Command}}, see config.example.yaml for all settings.
+# Command line flags override these values.
+connection:
+{{- with .Connection}}
+{{- if ne .Dialect "postgres"}}
+  dialect: {{quote .Dialect}}
+{{- end}}
+{{- if .Driver}}
+  driver: {{quote .Driver}}
+{{- end}}
+{{- if .DSN}}
+  dsn: {{quote .DSN}}
+{{- end}}
+{{- if .Service}}
+  service: {{quote .Service}}
+{{- end}}
+{{- if .Host}}
+  host: {{quote .Host}}
+{{- end}}
+{{- if .Port}}
+  port: {{quote .Port}}
+{{- end}}
+  user: {{quote .User}}
+  password: ""  # falls back to ~/.pgpass
+  database: {{quote .Database}}
+  sslmode: {{quote .SSLMode}}
+{{- end}}
+
+schemas: [{{range $i, $s := .Schemas}}{{if $i}}, {{end}}{{quote $s}}{{end}}]
+include_tables: []
+exclude_tables: [{{range $i, $t := .Exclude}}{{if $i}}, {{end}}{{quote $t}}{{end}}]
+
+# sql type (udt name) or table.column -> go type
+{{- if .Unmapped}}
+# TODO: the types below have no built-in mapping, replace the placeholders.
+types:
+{{- range .Unmapped}}
+  {{quote .Name}}: "interface{}"  # {{join .Columns ", "}}
+{{- end}}
+{{- else}}
+types: {}
+{{- end}}
+
+format: go
+
+output:
+  dir: models
+
+go:
+  package: models
+`
+
+// migrationTables are the bookkeeping tables of common migration tools,
+// init excludes the ones it finds.
+var migrationTables = []string{
+	"schema_migrations",
+	"goose_db_version",
+	"gorp_migrations",
+	"flyway_schema_history",
+	"ar_internal_metadata",
+	"knex_migrations",
+	"knex_migrations_lock",
+	"__diesel_schema_migrations",
+	"atlas_schema_revisions",
+}
+
+// UnmappedType is a column type without a Go mapping and the columns using it.
+type UnmappedType struct {
+	Name    string
+	Columns []string
+}
+
+func init() {
+	commands = append(commands, &Command{
+		Name:    "init",
+		Summary: "inspect the database and write a starter config file, to the given path or ./config",
+		Flags:   initFlags,
+		Run:     runInit,
+	})
+}
+
+var initForce bool
+
+func initFlags(cfg *Config, fs *flag.FlagSet) {
+	cfg.RegisterFlags(fs)
+	fs.BoolVar(&initForce, "force", false, "overwrite an existing config file")
+}
+
+func runInit(ctx context.Context, name string, args []string) error {
+	var fs *flag.FlagSet
+	cfg, err := loadConfig(name, args, func(cfg *Config, f *flag.FlagSet) {
+		initFlags(cfg, f)
+		fs = f
+	})
+	if err != nil {
+		return err
+	}
+	target := "config"
+	if fs.NArg() > 0 {
+		target = fs.Arg(0)
+	}
+	if _, err := os.Stat(target); err == nil && !initForce {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", target)
+	}
+
+	db, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schemas, err := db.Schemas(ctx)
+	if err != nil {
+		return err
+	}
+	if len(schemas) == 0 {
+		return fmt.Errorf("no tables found in database %s", cfg.Connection.DatabaseName())
+	}
+	tables, err := db.Tables(ctx, schemas)
+	if err != nil {
+		return err
+	}
+	dbEnums, err := db.Enums(ctx, schemas)
+	if err != nil {
+		return err
+	}
+	typer := db.Dialect().Types()
+	codegen.AddEnums(typer, codegen.Enums(dbEnums, codegen.EnumNaming{}))
+
+	found := make(map[string]bool)
+	unmapped := make(map[string][]string)
+	for _, columns := range tables {
+		found[columns[0].TableName] = true
+		for _, col := range columns {
+			if _, err := typer.GetType(col.UDTName); err != nil {
+				unmapped[col.UDTName] = append(unmapped[col.UDTName], col.TableSchema+"."+col.TableName+"."+col.ColumnName)
+			}
+		}
+	}
+	var exclude []string
+	for _, migration := range migrationTables {
+		if found[migration] {
+			exclude = append(exclude, migration)
+		}
+	}
+
+	var types []UnmappedType
+	for udt, columns := range unmapped {
+		sort.Strings(columns)
+		if len(columns) > 3 {
+			columns = append(columns[:3], fmt.Sprintf("%d more", len(columns)-3))
+		}
+		types = append(types, UnmappedType{Name: udt, Columns: columns})
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+
+	t := template.Must(template.New("config").Funcs(template.FuncMap{
+		"quote": strconv.Quote,
+		"join":  strings.Join,
+	}).Parse(initConfigTpl))
+	var buf strings.Builder
+	if err := t.Execute(&buf, map[string]interface{}{
+		"Command":    strings.Fields(name)[0] + " init",
+		"Connection": cfg.Connection,
+		"Schemas":    schemas,
+		"Exclude":    exclude,
+		"Unmapped":   types,
+	}); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(target, []byte(buf.String()), 0644); err != nil {
+		return err
+	}
+
+	if !cfg.Quiet {
+		fmt.Printf("wrote %s: %d schemas, %d tables, %d unmapped types\n", target, len(schemas), len(tables), len(types))
+	}
+	return nil
+}
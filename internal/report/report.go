@@ -0,0 +1,75 @@
+// Package report holds the diagnostics shared by the generator packages: a
+// debug log and a progress line. Both discard everything until the command
+// line tool enables them.
+package report
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// Debug traces what the generator does, the tool writes it with -v.
+var Debug = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// Steps shows how far the current step of a run got, so that long runs on
+// large schemas don't look hung. The tool only enables it when stderr is a
+// terminal and neither -q nor -v is given.
+var Steps = &Progress{w: ioutil.Discard}
+
+// Progress reports a step on a single line that is rewritten as items get done.
+type Progress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	step  string
+	done  int
+	total int
+	shown time.Time
+}
+
+// Start begins a step of total items, 0 if the total isn't known up front.
+func (p *Progress) Start(step string, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.step, p.done, p.total = step, 0, total
+	p.shown = time.Time{}
+	p.show()
+}
+
+// Add counts n more items as done.
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	// redrawing on every item would slow down the run it reports on
+	if time.Since(p.shown) >= 100*time.Millisecond || p.done == p.total {
+		p.show()
+	}
+}
+
+// Finish shows the final count of the step and ends its line.
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.show()
+	fmt.Fprintln(p.w)
+}
+
+// SetOutput enables progress reports on w.
+func (p *Progress) SetOutput(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.w = w
+}
+
+func (p *Progress) show() {
+	p.shown = time.Now()
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "\r\033[K%s: %d/%d", p.step, p.done, p.total)
+	} else {
+		fmt.Fprintf(p.w, "\r\033[K%s: %d", p.step, p.done)
+	}
+}
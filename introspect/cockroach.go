@@ -0,0 +1,144 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// Cockroach lacks some of the catalog functions the Postgres queries use, so
+// these stick to the information schema where it can.
+const (
+	cockroachTablesQuery = `
+SELECT
+	c.table_schema, c.table_name, c.column_name, c.ordinal_position, c.column_default, c.is_nullable = 'YES', c.data_type, c.udt_name,
+	c.character_maximum_length, c.character_octet_length, c.numeric_precision, c.numeric_scale,
+	(
+		SELECT d.description FROM pg_description AS d
+		JOIN pg_class AS cl ON cl.oid = d.objoid
+		JOIN pg_namespace AS n ON n.oid = cl.relnamespace
+		WHERE n.nspname = c.table_schema AND cl.relname = c.table_name AND d.objsubid = c.ordinal_position
+	),
+	EXISTS (
+		SELECT 1 FROM information_schema.table_constraints AS tc
+		JOIN information_schema.key_column_usage AS k
+			ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name AND k.table_name = tc.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema AND tc.table_name = c.table_name
+			AND k.column_name = c.column_name
+	)
+FROM
+	information_schema.columns AS c
+JOIN
+	information_schema.tables AS t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE
+	t.table_schema = ANY($1) AND t.table_type = 'BASE TABLE' AND c.is_hidden = 'NO'
+ORDER BY
+	c.table_schema, c.table_name, c.ordinal_position;
+`
+
+	cockroachForeignKeysQuery = `
+SELECT
+	k.constraint_name, k.table_schema, k.table_name, k.column_name, r.table_schema, r.table_name, r.column_name
+FROM
+	information_schema.referential_constraints AS rc
+JOIN
+	information_schema.key_column_usage AS k
+	ON k.constraint_schema = rc.constraint_schema AND k.constraint_name = rc.constraint_name AND k.table_name = rc.table_name
+JOIN
+	information_schema.key_column_usage AS r
+	ON r.constraint_schema = rc.unique_constraint_schema AND r.constraint_name = rc.unique_constraint_name
+		AND r.table_name = rc.referenced_table_name AND r.ordinal_position = k.position_in_unique_constraint
+WHERE
+	k.table_schema = ANY($1)
+ORDER BY
+	k.table_schema, k.table_name, k.constraint_name, k.ordinal_position;
+`
+
+	cockroachSchemasQuery = `
+SELECT DISTINCT
+	table_schema
+FROM
+	information_schema.tables
+WHERE
+	table_type = 'BASE TABLE' AND table_schema NOT IN ('information_schema', 'pg_catalog', 'crdb_internal', 'pg_extension')
+ORDER BY
+	table_schema;
+`
+
+	cockroachSchemaHashQuery = `
+SELECT
+	concat_ws(' ', 'column', table_schema, table_name, column_name, ordinal_position::STRING, udt_name,
+		is_nullable, column_default, character_maximum_length::STRING)
+FROM
+	information_schema.columns
+WHERE
+	table_schema = ANY($1) AND is_hidden = 'NO'
+UNION ALL
+SELECT
+	concat_ws(' ', 'constraint', k.table_schema, k.table_name, k.constraint_name, k.column_name, k.ordinal_position::STRING)
+FROM
+	information_schema.key_column_usage AS k
+WHERE
+	k.table_schema = ANY($1)
+UNION ALL
+SELECT
+	concat_ws(' ', 'enum', t.typname, e.enumlabel)
+FROM
+	pg_enum AS e
+JOIN
+	pg_type AS t ON t.oid = e.enumtypid
+JOIN
+	pg_namespace AS n ON n.oid = t.typnamespace
+WHERE
+	n.nspname = ANY($1)
+UNION ALL
+SELECT
+	concat_ws(' ', 'comment', n.nspname, c.relname, d.objsubid::STRING, d.description)
+FROM
+	pg_description AS d
+JOIN
+	pg_class AS c ON c.oid = d.objoid
+JOIN
+	pg_namespace AS n ON n.oid = c.relnamespace
+WHERE
+	n.nspname = ANY($1)
+ORDER BY
+	1;
+`
+)
+
+// Cockroach reads CockroachDB through its Postgres compatible catalogs. Hidden
+// columns such as rowid are left out.
+var Cockroach Dialect = cockroach{}
+
+// cockroach shares table comments and enums with postgres.
+type cockroach struct {
+	postgres
+}
+
+func (cockroach) Name() string {
+	return "cockroach"
+}
+
+func (cockroach) Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error) {
+	return queryColumns(ctx, db, cockroachTablesQuery, pq.Array(schemas))
+}
+
+func (cockroach) ForeignKeys(ctx context.Context, db *sql.DB, schemas []string) ([]DBForeignKey, error) {
+	return queryForeignKeys(ctx, db, cockroachForeignKeysQuery, pq.Array(schemas))
+}
+
+func (cockroach) Schemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "schemas", cockroachSchemasQuery)
+}
+
+func (cockroach) SchemaHash(ctx context.Context, db *sql.DB, schemas []string) (string, error) {
+	return queryHash(ctx, db, cockroachSchemaHashQuery, pq.Array(schemas))
+}
+
+func (cockroach) Types() *typemap.TypesMapping {
+	return typemap.NewCockroachTypesMapping()
+}
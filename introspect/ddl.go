@@ -0,0 +1,1661 @@
+package introspect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ddlTypes maps Postgres type names and aliases to their udt names.
+var ddlTypes = map[string]string{
+	"int":                         "int4",
+	"integer":                     "int4",
+	"int4":                        "int4",
+	"serial":                      "int4",
+	"serial4":                     "int4",
+	"smallint":                    "int2",
+	"int2":                        "int2",
+	"smallserial":                 "int2",
+	"serial2":                     "int2",
+	"bigint":                      "int8",
+	"int8":                        "int8",
+	"bigserial":                   "int8",
+	"serial8":                     "int8",
+	"boolean":                     "bool",
+	"bool":                        "bool",
+	"character varying":           "varchar",
+	"varchar":                     "varchar",
+	"character":                   "bpchar",
+	"char":                        "bpchar",
+	"bpchar":                      "bpchar",
+	"timestamp":                   "timestamp",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"timestamptz":                 "timestamptz",
+	"time":                        "time",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+	"timetz":                      "timetz",
+	"numeric":                     "numeric",
+	"decimal":                     "numeric",
+	"real":                        "float4",
+	"float4":                      "float4",
+	"double precision":            "float8",
+	"float8":                      "float8",
+	"float":                       "float8",
+}
+
+// ddlSerials are the types implying a sequence default.
+var ddlSerials = map[string]bool{
+	"serial": true, "serial2": true, "serial4": true, "serial8": true,
+	"smallserial": true, "bigserial": true,
+}
+
+// ddlColumnKeywords end a column's type or default expression.
+var ddlColumnKeywords = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "REFERENCES": true, "UNIQUE": true,
+	"CHECK": true, "CONSTRAINT": true, "COLLATE": true, "GENERATED": true, "USING": true,
+}
+
+type ddlToken struct {
+	text string
+	// kind is 'w' for words, 'i' for quoted identifiers, 's' for strings
+	// and 'p' for punctuation
+	kind byte
+	line int
+	// space is whether whitespace or a comment comes before the token
+	space bool
+}
+
+// lexDDL splits src into statements of tokens, dropping comments and psql
+// meta-commands.
+func lexDDL(src string) ([][]ddlToken, error) {
+	var (
+		stmts  [][]ddlToken
+		stmt   []ddlToken
+		line   = 1
+		spaced bool
+	)
+	emit := func(text string, kind byte) {
+		stmt = append(stmt, ddlToken{text, kind, line, spaced})
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		start, tokens := i, len(stmt)
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '\\' && (i == 0 || src[i-1] == '\n'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			i += end + 4
+		case c == '\'' || ((c == 'E' || c == 'e') && i+1 < len(src) && src[i+1] == '\''):
+			if c != '\'' {
+				i++
+			}
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("line %d: unterminated string", line)
+				}
+				if src[i] == '\'' {
+					if i+1 < len(src) && src[i+1] == '\'' {
+						b.WriteByte('\'')
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+			}
+			emit(b.String(), 's')
+		case c == '"':
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("line %d: unterminated identifier", line)
+				}
+				if src[i] == '"' {
+					if i+1 < len(src) && src[i+1] == '"' {
+						b.WriteByte('"')
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+			}
+			emit(b.String(), 'i')
+		case c == '$' && i+1 < len(src) && !isDigit(src[i+1]):
+			end := strings.IndexByte(src[i+1:], '$')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated dollar quote", line)
+			}
+			tag := src[i : i+end+2]
+			body := strings.Index(src[i+len(tag):], tag)
+			if body < 0 {
+				return nil, fmt.Errorf("line %d: unterminated dollar quote", line)
+			}
+			text := src[i+len(tag) : i+len(tag)+body]
+			emit(text, 's')
+			i += len(tag)*2 + body
+		case isWordByte(c):
+			for i < len(src) && isWordByte(src[i]) {
+				i++
+			}
+			emit(src[start:i], 'w')
+		case c == ';':
+			if len(stmt) > 0 {
+				stmts = append(stmts, stmt)
+			}
+			i++
+			// the rows of COPY FROM stdin in a data dump follow up to \.
+			if isCopyFromStdin(stmt) {
+				end := strings.Index(src[i:], "\n\\.")
+				if end < 0 {
+					return nil, fmt.Errorf("line %d: unterminated COPY data", line)
+				}
+				i += end + 3
+			}
+			stmt = nil
+		case strings.HasPrefix(src[i:], "::"):
+			emit("::", 'p')
+			i += 2
+		default:
+			emit(string(c), 'p')
+			i++
+		}
+		line += strings.Count(src[start:i], "\n")
+		if c == '\n' {
+			line--
+		}
+		spaced = len(stmt) <= tokens
+	}
+	if len(stmt) > 0 {
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+func isCopyFromStdin(stmt []ddlToken) bool {
+	n := len(stmt)
+	return n > 2 && strings.EqualFold(stmt[0].text, "COPY") &&
+		strings.EqualFold(stmt[n-2].text, "FROM") && strings.EqualFold(stmt[n-1].text, "stdin")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// ddlParser applies statements to the schema being built. Tables keep the
+// order they were created in.
+type ddlParser struct {
+	tokens []ddlToken
+	pos    int
+
+	tables   map[string]*ddlTable
+	order    []string
+	fks      []DBForeignKey
+	comments map[string]string
+	enums    DBEnums
+	triggers DBTriggers
+	security map[string]*ddlSecurity
+	// sequences are those created by CREATE SEQUENCE, keyed by
+	// "schema.sequence"
+	sequences map[string]bool
+}
+
+func newDDLParser() *ddlParser {
+	return &ddlParser{
+		tables:    make(map[string]*ddlTable),
+		comments:  make(map[string]string),
+		enums:     make(DBEnums),
+		triggers:  make(DBTriggers),
+		security:  make(map[string]*ddlSecurity),
+		sequences: make(map[string]bool),
+	}
+}
+
+type ddlTable struct {
+	schema, name string
+	columns      []DBColumn
+	// sequences are the "schema.sequence" of serial and identity columns by
+	// column name, they go with the table and keep their name on renames
+	sequences map[string]string
+}
+
+// ddlSecurity is the row level security of a table, policies may be created
+// before it's enabled.
+type ddlSecurity struct {
+	enabled bool
+	DBTableSecurity
+}
+
+func (p *ddlParser) peek() ddlToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ddlToken{}
+}
+
+func (p *ddlParser) next() ddlToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// is reports whether the next tokens are the keywords or punctuation words.
+func (p *ddlParser) is(words ...string) bool {
+	for i, w := range words {
+		if p.pos+i >= len(p.tokens) {
+			return false
+		}
+		t := p.tokens[p.pos+i]
+		if t.kind == 'i' || t.kind == 's' || !strings.EqualFold(t.text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// accept consumes words if they are next.
+func (p *ddlParser) accept(words ...string) bool {
+	if !p.is(words...) {
+		return false
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *ddlParser) expect(words ...string) error {
+	if !p.accept(words...) {
+		return p.errorf("expected %s", strings.Join(words, " "))
+	}
+	return nil
+}
+
+func (p *ddlParser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	if p.pos >= len(p.tokens) && len(p.tokens) > 0 {
+		t = p.tokens[len(p.tokens)-1]
+		return fmt.Errorf("line %d: %s at end of statement", t.line, fmt.Sprintf(format, args...))
+	}
+	return fmt.Errorf("line %d: %s, found %q", t.line, fmt.Sprintf(format, args...), t.text)
+}
+
+// ident reads an identifier, folding unquoted ones to lower case.
+func (p *ddlParser) ident() (string, error) {
+	t := p.peek()
+	switch t.kind {
+	case 'i':
+		p.pos++
+		return t.text, nil
+	case 'w':
+		p.pos++
+		return strings.ToLower(t.text), nil
+	}
+	return "", p.errorf("expected a name")
+}
+
+// name reads a possibly qualified name, returning its parts.
+func (p *ddlParser) name() ([]string, error) {
+	var parts []string
+	for {
+		part, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if !p.accept(".") {
+			return parts, nil
+		}
+	}
+}
+
+// tableName reads a table name, unqualified names are in public.
+func (p *ddlParser) tableName() (schema, table string, err error) {
+	parts, err := p.name()
+	if err != nil {
+		return "", "", err
+	}
+	if len(parts) == 1 {
+		return "public", parts[0], nil
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
+
+// skip consumes tokens up to one of the stop words or punctuation at the
+// current nesting level, returning them.
+func (p *ddlParser) skip(stop func(ddlToken) bool) []ddlToken {
+	start, depth := p.pos, 0
+	for p.pos < len(p.tokens) {
+		t := p.peek()
+		if depth == 0 && stop(t) {
+			break
+		}
+		if t.kind == 'p' && (t.text == "(" || t.text == "[") {
+			depth++
+		}
+		if t.kind == 'p' && (t.text == ")" || t.text == "]") {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		p.pos++
+	}
+	return p.tokens[start:p.pos]
+}
+
+// skipParens consumes a parenthesized group if one is next.
+func (p *ddlParser) skipParens() {
+	if p.accept("(") {
+		p.skip(func(ddlToken) bool { return false })
+		p.accept(")")
+	}
+}
+
+// nameList reads a parenthesized list of column names.
+func (p *ddlParser) nameList() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		// index options such as ASC or a collation
+		p.skip(func(t ddlToken) bool { return t.kind == 'p' && t.text == "," })
+		if !p.accept(",") {
+			break
+		}
+	}
+	return names, p.expect(")")
+}
+
+func endOfItem(t ddlToken) bool {
+	return t.kind == 'p' && t.text == ","
+}
+
+func columnKeyword(t ddlToken) bool {
+	return endOfItem(t) || t.kind == 'w' && ddlColumnKeywords[strings.ToUpper(t.text)]
+}
+
+// joinTokens renders tokens back as SQL as they were written, whitespace
+// and comments between them collapsed to a space.
+func joinTokens(tokens []ddlToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.space {
+			b.WriteByte(' ')
+		}
+		switch t.kind {
+		case 's':
+			b.WriteString("'" + strings.Replace(t.text, "'", "''", -1) + "'")
+		case 'i':
+			b.WriteString(`"` + strings.Replace(t.text, `"`, `""`, -1) + `"`)
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+func (p *ddlParser) table(schema, name string) (*ddlTable, error) {
+	t, ok := p.tables[schema+"."+name]
+	if !ok {
+		return nil, p.errorf("unknown table %s.%s", schema, name)
+	}
+	return t, nil
+}
+
+func (t *ddlTable) column(name string) *DBColumn {
+	for i := range t.columns {
+		if t.columns[i].ColumnName == name {
+			return &t.columns[i]
+		}
+	}
+	return nil
+}
+
+func (t *ddlTable) setPrimaryKey(columns []string) {
+	for _, name := range columns {
+		if col := t.column(name); col != nil {
+			col.IsPrimaryKey = true
+			col.IsNullable = false
+		}
+	}
+}
+
+// statement applies one statement, statements that don't change tables,
+// columns, keys, enums or comments are ignored.
+func (p *ddlParser) statement(tokens []ddlToken) error {
+	p.tokens, p.pos = tokens, 0
+	switch {
+	case p.accept("CREATE"):
+		p.accept("OR", "REPLACE")
+		for p.accept("UNLOGGED") || p.accept("TEMP") || p.accept("TEMPORARY") || p.accept("GLOBAL") || p.accept("LOCAL") {
+		}
+		if p.accept("TABLE") {
+			return p.createTable()
+		}
+		if p.accept("TYPE") {
+			return p.createType()
+		}
+		if p.accept("SEQUENCE") {
+			return p.createSequence()
+		}
+		p.accept("CONSTRAINT")
+		if p.accept("TRIGGER") {
+			return p.createTrigger()
+		}
+		if p.accept("POLICY") {
+			return p.createPolicy()
+		}
+	case p.accept("ALTER", "TABLE"):
+		return p.alterTable()
+	case p.accept("ALTER", "TYPE"):
+		return p.alterType()
+	case p.accept("DROP", "TABLE"):
+		return p.dropTable()
+	case p.accept("DROP", "TYPE"):
+		p.accept("IF", "EXISTS")
+		for {
+			parts, err := p.name()
+			if err != nil {
+				return err
+			}
+			delete(p.enums, parts[len(parts)-1])
+			if !p.accept(",") {
+				return nil
+			}
+		}
+	case p.accept("DROP", "SEQUENCE"):
+		return p.dropSequence()
+	case p.accept("DROP", "TRIGGER"):
+		return p.dropTrigger()
+	case p.accept("ALTER", "TRIGGER"):
+		return p.alterTrigger()
+	case p.accept("DROP", "POLICY"):
+		return p.dropPolicy()
+	case p.accept("ALTER", "POLICY"):
+		return p.alterPolicy()
+	case p.accept("COMMENT", "ON"):
+		return p.comment()
+	}
+	return nil
+}
+
+// createSequence records a sequence, whose name serial and identity columns
+// then can't take.
+func (p *ddlParser) createSequence() error {
+	p.accept("IF", "NOT", "EXISTS")
+	schema, name, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	p.sequences[schema+"."+name] = true
+	return nil
+}
+
+func (p *ddlParser) dropSequence() error {
+	p.accept("IF", "EXISTS")
+	for {
+		schema, name, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		delete(p.sequences, schema+"."+name)
+		if !p.accept(",") {
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) createTable() error {
+	p.accept("IF", "NOT", "EXISTS")
+	schema, name, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	// CREATE TABLE ... AS and PARTITION OF have no column list to read
+	if !p.accept("(") {
+		return nil
+	}
+	key := schema + "." + name
+	if _, ok := p.tables[key]; !ok {
+		p.order = append(p.order, key)
+	}
+	t := &ddlTable{schema: schema, name: name, sequences: make(map[string]string)}
+	p.tables[key] = t
+
+	for !p.is(")") {
+		if err := p.tableElement(t); err != nil {
+			return err
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	return p.expect(")")
+}
+
+// tableElement reads a column definition or a table constraint.
+func (p *ddlParser) tableElement(t *ddlTable) error {
+	switch {
+	case p.is("CONSTRAINT"), p.is("PRIMARY", "KEY"), p.is("FOREIGN", "KEY"), p.is("UNIQUE"), p.is("CHECK"), p.is("EXCLUDE"):
+		return p.tableConstraint(t)
+	case p.is("LIKE"):
+		p.skip(endOfItem)
+		return nil
+	}
+	col, err := p.columnDef(t)
+	if err != nil {
+		return err
+	}
+	t.columns = append(t.columns, col)
+	return nil
+}
+
+func (p *ddlParser) tableConstraint(t *ddlTable) error {
+	name := ""
+	if p.accept("CONSTRAINT") {
+		var err error
+		if name, err = p.ident(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case p.accept("PRIMARY", "KEY"):
+		columns, err := p.nameList()
+		if err != nil {
+			return err
+		}
+		t.setPrimaryKey(columns)
+	case p.accept("FOREIGN", "KEY"):
+		columns, err := p.nameList()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("REFERENCES"); err != nil {
+			return err
+		}
+		if name == "" {
+			name = t.name + "_" + strings.Join(columns, "_") + "_fkey"
+		}
+		if err := p.references(t, name, columns); err != nil {
+			return err
+		}
+	}
+	p.skip(endOfItem)
+	return nil
+}
+
+// references reads the target of a foreign key on columns of t.
+func (p *ddlParser) references(t *ddlTable, name string, columns []string) error {
+	refSchema, refTable, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	var refColumns []string
+	if p.is("(") {
+		if refColumns, err = p.nameList(); err != nil {
+			return err
+		}
+	} else if ref, ok := p.tables[refSchema+"."+refTable]; ok {
+		// without columns the primary key is referenced
+		for _, col := range ref.columns {
+			if col.IsPrimaryKey {
+				refColumns = append(refColumns, col.ColumnName)
+			}
+		}
+	}
+	if len(refColumns) != len(columns) {
+		return p.errorf("foreign key %s references %d columns for %d", name, len(refColumns), len(columns))
+	}
+	for i := range columns {
+		p.fks = append(p.fks, DBForeignKey{
+			ConstraintName: name,
+			Schema:         t.schema,
+			TableName:      t.name,
+			ColumnName:     columns[i],
+			RefSchema:      refSchema,
+			RefTableName:   refTable,
+			RefColumnName:  refColumns[i],
+		})
+	}
+	// MATCH, ON DELETE, DEFERRABLE and the like
+	for p.pos < len(p.tokens) && !columnKeyword(p.peek()) && !p.is(")") {
+		p.pos++
+	}
+	return nil
+}
+
+// columnType reads a type and returns its udt name and data type.
+func (p *ddlParser) columnType(col *DBColumn) error {
+	var (
+		words  []string
+		quoted bool
+	)
+	for p.pos < len(p.tokens) {
+		t := p.peek()
+		if t.kind == 'p' && t.text == "." {
+			// a qualified type, only its name counts
+			p.pos++
+			words = words[:len(words)-1]
+			continue
+		}
+		if t.kind != 'w' && t.kind != 'i' || columnKeyword(t) || p.is("ARRAY") {
+			break
+		}
+		quoted = t.kind == 'i'
+		if quoted {
+			words = append(words, t.text)
+		} else {
+			words = append(words, strings.ToLower(t.text))
+		}
+		p.pos++
+	}
+	if len(words) == 0 {
+		return p.errorf("expected a type")
+	}
+	typeName := strings.Join(words, " ")
+	if p.accept("(") {
+		args := p.skip(func(ddlToken) bool { return false })
+		if err := p.expect(")"); err != nil {
+			return err
+		}
+		if n, err := strconv.Atoi(args[0].text); err == nil {
+			switch typeName {
+			case "numeric", "decimal":
+				// numeric(12) is numeric(12,0)
+				scale := 0
+				if len(args) == 3 {
+					scale, _ = strconv.Atoi(args[2].text)
+				}
+				col.NumericPrecision, col.NumericScale = &n, &scale
+			case "character varying", "varchar", "character", "char", "bpchar", "bit", "bit varying", "varbit", "vector":
+				col.CharacterMaximumLength = &n
+			}
+		}
+		// timestamp(3) with time zone puts the precision in the middle
+		for p.peek().kind == 'w' && !columnKeyword(p.peek()) && !p.is("ARRAY") {
+			typeName += " " + strings.ToLower(p.next().text)
+		}
+	}
+	array := false
+	for p.accept("[") {
+		p.skip(func(ddlToken) bool { return false })
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+		array = true
+	}
+	if p.accept("ARRAY") {
+		array = true
+		if p.accept("[") {
+			p.skip(func(ddlToken) bool { return false })
+			p.accept("]")
+		}
+	}
+
+	// quoted names aren't aliases, "char" is a type of its own
+	udt, ok := ddlTypes[typeName]
+	if !ok || quoted && len(words) == 1 {
+		udt = typeName
+	}
+	col.DataType = typeName
+	if array {
+		udt = "_" + udt
+		col.DataType = "ARRAY"
+	}
+	col.UDTName = udt
+	return nil
+}
+
+func (p *ddlParser) columnDef(t *ddlTable) (DBColumn, error) {
+	name, err := p.ident()
+	if err != nil {
+		return DBColumn{}, err
+	}
+	col := DBColumn{
+		TableSchema: t.schema,
+		TableName:   t.name,
+		ColumnName:  name,
+		IsNullable:  true,
+	}
+	if err := p.columnType(&col); err != nil {
+		return DBColumn{}, err
+	}
+	if ddlSerials[col.DataType] {
+		// qualified outside public, like Postgres shows the default
+		seq := quoteIdent(p.ownSequence(t, name, t.schema, ""))
+		if t.schema != "public" {
+			seq = quoteIdent(t.schema) + "." + seq
+		}
+		def := fmt.Sprintf("nextval('%s'::regclass)", strings.ReplaceAll(seq, "'", "''"))
+		col.ColumnDefault = &def
+		col.IsNullable = false
+	}
+	return col, p.columnConstraints(t, &col)
+}
+
+// ownSequence records the sequence of column of t and returns its name:
+// name if given, else the one Postgres makes up, table_column_seq cut to fit
+// in 63 bytes and numbered if taken.
+func (p *ddlParser) ownSequence(t *ddlTable, column, schema, name string) string {
+	for pass := 0; name == ""; pass++ {
+		label := "seq"
+		if pass > 0 {
+			label += strconv.Itoa(pass)
+		}
+		if candidate := objectName(t.name, column, label); !p.relationExists(schema, candidate) {
+			name = candidate
+		}
+	}
+	t.sequences[column] = schema + "." + name
+	return name
+}
+
+// relationExists reports whether a table or sequence of that name is known
+// in schema.
+func (p *ddlParser) relationExists(schema, name string) bool {
+	key := schema + "." + name
+	if p.tables[key] != nil || p.sequences[key] {
+		return true
+	}
+	for _, t := range p.tables {
+		for _, seq := range t.sequences {
+			if seq == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// objectName joins name1, name2 and label with underscores as Postgres names
+// implicit objects, cutting the longer name until the result fits in 63
+// bytes.
+func objectName(name1, name2, label string) string {
+	n1, n2 := len(name1), len(name2)
+	for n1+n2 > 63-len(label)-2 {
+		if n1 > n2 {
+			n1--
+		} else {
+			n2--
+		}
+	}
+	clip := func(s string, n int) string {
+		for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		return s[:n]
+	}
+	return clip(name1, n1) + "_" + clip(name2, n2) + "_" + label
+}
+
+// columnConstraints reads the constraints following a column's type.
+func (p *ddlParser) columnConstraints(t *ddlTable, col *DBColumn) error {
+	for p.pos < len(p.tokens) && !p.is(",") && !p.is(")") {
+		constraint := ""
+		if p.accept("CONSTRAINT") {
+			var err error
+			if constraint, err = p.ident(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case p.accept("NOT", "NULL"):
+			col.IsNullable = false
+		case p.accept("NULL"):
+		case p.accept("PRIMARY", "KEY"):
+			col.IsPrimaryKey = true
+			col.IsNullable = false
+		case p.accept("DEFAULT"):
+			def := joinTokens(p.skip(columnKeyword))
+			col.ColumnDefault = &def
+		case p.accept("GENERATED"):
+			// identity and generated columns are filled in by the database
+			def, err := p.generated(t, col.ColumnName, columnKeyword)
+			if err != nil {
+				return err
+			}
+			col.ColumnDefault = &def
+		case p.accept("REFERENCES"):
+			if constraint == "" {
+				constraint = t.name + "_" + col.ColumnName + "_fkey"
+			}
+			if err := p.references(t, constraint, []string{col.ColumnName}); err != nil {
+				return err
+			}
+		case p.accept("UNIQUE"), p.accept("CHECK"), p.accept("COLLATE"):
+			p.skip(columnKeyword)
+		default:
+			return p.errorf("unexpected token in definition of column %s", col.ColumnName)
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) createType() error {
+	parts, err := p.name()
+	if err != nil {
+		return err
+	}
+	if !p.accept("AS", "ENUM") {
+		return nil
+	}
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	name := parts[len(parts)-1]
+	p.enums[name] = nil
+	for p.peek().kind == 's' {
+		p.enums[name] = append(p.enums[name], p.next().text)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return p.expect(")")
+}
+
+func (p *ddlParser) alterType() error {
+	parts, err := p.name()
+	if err != nil {
+		return err
+	}
+	name := parts[len(parts)-1]
+	labels, ok := p.enums[name]
+	if !ok || !p.accept("ADD", "VALUE") {
+		return nil
+	}
+	p.accept("IF", "NOT", "EXISTS")
+	if p.peek().kind != 's' {
+		return p.errorf("expected a label")
+	}
+	label := p.next().text
+	for _, l := range labels {
+		if l == label {
+			return nil
+		}
+	}
+	at := len(labels)
+	before := p.accept("BEFORE")
+	if before || p.accept("AFTER") {
+		other := p.next().text
+		for i, l := range labels {
+			if l == other {
+				at = i
+				if !before {
+					at++
+				}
+			}
+		}
+	}
+	labels = append(labels[:at], append([]string{label}, labels[at:]...)...)
+	p.enums[name] = labels
+	return nil
+}
+
+func (p *ddlParser) dropTable() error {
+	p.accept("IF", "EXISTS")
+	for {
+		schema, name, err := p.tableName()
+		if err != nil {
+			return err
+		}
+		key := schema + "." + name
+		delete(p.tables, key)
+		for i, k := range p.order {
+			if k == key {
+				p.order = append(p.order[:i:i], p.order[i+1:]...)
+				break
+			}
+		}
+		fks := p.fks[:0]
+		for _, fk := range p.fks {
+			if fk.Schema+"."+fk.TableName != key {
+				fks = append(fks, fk)
+			}
+		}
+		p.fks = fks
+		delete(p.triggers, key)
+		delete(p.security, key)
+		if !p.accept(",") {
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) alterTable() error {
+	p.accept("IF", "EXISTS")
+	p.accept("ONLY")
+	schema, name, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	t, ok := p.tables[schema+"."+name]
+	if !ok {
+		// e.g. a partition or a table of another dump section
+		return nil
+	}
+	if p.accept("RENAME", "TO") {
+		newName, err := p.ident()
+		if err != nil {
+			return err
+		}
+		p.renameTable(t, newName)
+		return nil
+	}
+
+	for {
+		if err := p.alterAction(t); err != nil {
+			return err
+		}
+		if !p.accept(",") {
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) renameTable(t *ddlTable, newName string) {
+	oldKey, newKey := t.schema+"."+t.name, t.schema+"."+newName
+	for i := range t.columns {
+		t.columns[i].TableName = newName
+	}
+	for i := range p.fks {
+		if p.fks[i].Schema+"."+p.fks[i].TableName == oldKey {
+			p.fks[i].TableName = newName
+		}
+		if p.fks[i].RefSchema+"."+p.fks[i].RefTableName == oldKey {
+			p.fks[i].RefTableName = newName
+		}
+	}
+	if comment, ok := p.comments[oldKey]; ok {
+		delete(p.comments, oldKey)
+		p.comments[newKey] = comment
+	}
+	if triggers, ok := p.triggers[oldKey]; ok {
+		delete(p.triggers, oldKey)
+		p.triggers[newKey] = triggers
+	}
+	if security, ok := p.security[oldKey]; ok {
+		delete(p.security, oldKey)
+		p.security[newKey] = security
+	}
+	delete(p.tables, oldKey)
+	t.name = newName
+	p.tables[newKey] = t
+	for i, k := range p.order {
+		if k == oldKey {
+			p.order[i] = newKey
+		}
+	}
+}
+
+func (p *ddlParser) alterAction(t *ddlTable) error {
+	switch {
+	case p.accept("ADD"):
+		if p.is("CONSTRAINT") || p.is("PRIMARY", "KEY") || p.is("FOREIGN", "KEY") || p.is("UNIQUE") || p.is("CHECK") || p.is("EXCLUDE") {
+			return p.tableConstraint(t)
+		}
+		p.accept("COLUMN")
+		p.accept("IF", "NOT", "EXISTS")
+		col, err := p.columnDef(t)
+		if err != nil {
+			return err
+		}
+		if t.column(col.ColumnName) == nil {
+			t.columns = append(t.columns, col)
+		}
+	case p.accept("DROP", "CONSTRAINT"):
+		p.accept("IF", "EXISTS")
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		fks := p.fks[:0]
+		for _, fk := range p.fks {
+			if fk.Schema != t.schema || fk.TableName != t.name || fk.ConstraintName != name {
+				fks = append(fks, fk)
+			}
+		}
+		p.fks = fks
+		p.skip(endOfItem)
+	case p.accept("DROP"):
+		p.accept("COLUMN")
+		p.accept("IF", "EXISTS")
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		for i, col := range t.columns {
+			if col.ColumnName == name {
+				t.columns = append(t.columns[:i:i], t.columns[i+1:]...)
+				break
+			}
+		}
+		delete(t.sequences, name)
+		p.skip(endOfItem)
+	case p.accept("RENAME"):
+		if p.accept("CONSTRAINT") {
+			p.skip(endOfItem)
+			return nil
+		}
+		p.accept("COLUMN")
+		oldName, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("TO"); err != nil {
+			return err
+		}
+		newName, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if col := t.column(oldName); col != nil {
+			col.ColumnName = newName
+		}
+		if seq, ok := t.sequences[oldName]; ok {
+			delete(t.sequences, oldName)
+			t.sequences[newName] = seq
+		}
+		for i, fk := range p.fks {
+			if fk.Schema == t.schema && fk.TableName == t.name && fk.ColumnName == oldName {
+				p.fks[i].ColumnName = newName
+			}
+			if fk.RefSchema == t.schema && fk.RefTableName == t.name && fk.RefColumnName == oldName {
+				p.fks[i].RefColumnName = newName
+			}
+		}
+	case p.accept("ALTER"):
+		p.accept("COLUMN")
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		col := t.column(name)
+		if col == nil {
+			return p.errorf("unknown column %s of table %s", name, t.name)
+		}
+		return p.alterColumn(t, col)
+	case p.accept("ENABLE", "ROW", "LEVEL", "SECURITY"):
+		p.tableSecurity(t.schema + "." + t.name).enabled = true
+	case p.accept("DISABLE", "ROW", "LEVEL", "SECURITY"):
+		p.tableSecurity(t.schema + "." + t.name).enabled = false
+	case p.accept("FORCE", "ROW", "LEVEL", "SECURITY"):
+		p.tableSecurity(t.schema + "." + t.name).Forced = true
+	case p.accept("NO", "FORCE", "ROW", "LEVEL", "SECURITY"):
+		p.tableSecurity(t.schema + "." + t.name).Forced = false
+	default:
+		// OWNER TO, SET, ENABLE TRIGGER and the like
+		p.skip(endOfItem)
+	}
+	return nil
+}
+
+// generated reads the clause after GENERATED of column of t up to stop.
+// Identity and generated column clauses are spelled as Postgres reports
+// them, identities with the name of their sequence as the only option, so
+// that parsed and introspected schemas compare equal.
+func (p *ddlParser) generated(t *ddlTable, column string, stop func(ddlToken) bool) (string, error) {
+	var generation string
+	switch {
+	case p.accept("ALWAYS", "AS", "IDENTITY"):
+		generation = "always"
+	case p.accept("BY", "DEFAULT", "AS", "IDENTITY"):
+		generation = "by default"
+	case p.accept("ALWAYS", "AS", "("):
+		expr := joinTokens(p.skip(func(ddlToken) bool { return false }))
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		// virtual unless stored, as of Postgres 18
+		kind := "virtual"
+		if p.accept("STORED") {
+			kind = "stored"
+		}
+		p.skip(stop)
+		return fmt.Sprintf("generated always as (%s) %s", expr, kind), nil
+	default:
+		return joinTokens(append([]ddlToken{{text: "generated", kind: 'w'}}, p.skip(stop)...)), nil
+	}
+	// the sequence goes into the table's schema unless named otherwise
+	schema, name := t.schema, ""
+	if p.accept("(") {
+		for p.pos < len(p.tokens) && !p.is(")") {
+			if !p.accept("SEQUENCE", "NAME") {
+				p.next()
+				continue
+			}
+			parts, err := p.name()
+			if err != nil {
+				return "", err
+			}
+			name = parts[len(parts)-1]
+			if len(parts) > 1 {
+				schema = parts[len(parts)-2]
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+	}
+	p.skip(stop)
+	name = p.ownSequence(t, column, schema, name)
+	return fmt.Sprintf("generated %s as identity (sequence name %s.%s)", generation, quoteIdent(schema), quoteIdent(name)), nil
+}
+
+func (p *ddlParser) alterColumn(t *ddlTable, col *DBColumn) error {
+	switch {
+	case p.accept("SET", "DEFAULT"):
+		def := joinTokens(p.skip(endOfItem))
+		col.ColumnDefault = &def
+	case p.accept("DROP", "DEFAULT"):
+		col.ColumnDefault = nil
+	case p.accept("SET", "NOT", "NULL"):
+		col.IsNullable = false
+	case p.accept("DROP", "NOT", "NULL"):
+		col.IsNullable = true
+	case p.accept("ADD", "GENERATED"):
+		def, err := p.generated(t, col.ColumnName, endOfItem)
+		if err != nil {
+			return err
+		}
+		col.ColumnDefault = &def
+	case p.accept("DROP", "IDENTITY"):
+		// the sequence goes with the identity
+		col.ColumnDefault = nil
+		delete(t.sequences, col.ColumnName)
+		p.skip(endOfItem)
+	case p.accept("SET", "DATA", "TYPE"), p.accept("TYPE"):
+		col.CharacterMaximumLength, col.NumericPrecision, col.NumericScale = nil, nil, nil
+		if err := p.columnType(col); err != nil {
+			return err
+		}
+		p.skip(endOfItem)
+	default:
+		p.skip(endOfItem)
+	}
+	return nil
+}
+
+// createTrigger reads what a trigger runs and when, the arguments of its
+// function and conditions are skipped.
+func (p *ddlParser) createTrigger() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	t := DBTrigger{Name: name, Level: "STATEMENT"}
+	switch {
+	case p.accept("BEFORE"):
+		t.Timing = "BEFORE"
+	case p.accept("AFTER"):
+		t.Timing = "AFTER"
+	case p.accept("INSTEAD", "OF"):
+		t.Timing = "INSTEAD OF"
+	default:
+		return p.errorf("expected BEFORE, AFTER or INSTEAD OF")
+	}
+	for {
+		event := strings.ToUpper(p.next().text)
+		switch event {
+		case "INSERT", "UPDATE", "DELETE", "TRUNCATE":
+		default:
+			p.pos--
+			return p.errorf("expected a trigger event")
+		}
+		t.Events = append(t.Events, event)
+		if event == "UPDATE" && p.accept("OF") {
+			p.skip(func(t ddlToken) bool {
+				return t.kind == 'w' && (strings.EqualFold(t.text, "OR") || strings.EqualFold(t.text, "ON"))
+			})
+		}
+		if !p.accept("OR") {
+			break
+		}
+	}
+	if err := p.expect("ON"); err != nil {
+		return err
+	}
+	schema, table, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	p.skip(func(t ddlToken) bool {
+		return t.kind == 'w' && (strings.EqualFold(t.text, "FOR") || strings.EqualFold(t.text, "EXECUTE"))
+	})
+	if p.accept("FOR") {
+		p.accept("EACH")
+		switch {
+		case p.accept("ROW"):
+			t.Level = "ROW"
+		case p.accept("STATEMENT"):
+		default:
+			return p.errorf("expected ROW or STATEMENT")
+		}
+		p.skip(func(t ddlToken) bool { return t.kind == 'w' && strings.EqualFold(t.text, "EXECUTE") })
+	}
+	if !p.accept("EXECUTE", "FUNCTION") && !p.accept("EXECUTE", "PROCEDURE") {
+		return p.errorf("expected EXECUTE FUNCTION")
+	}
+	parts, err := p.name()
+	if err != nil {
+		return err
+	}
+	t.Function = strings.Join(parts, ".")
+
+	p.removeTrigger(schema+"."+table, name)
+	p.addTrigger(schema+"."+table, t)
+	return nil
+}
+
+// addTrigger adds t to the triggers of the table key, kept in name order.
+func (p *ddlParser) addTrigger(key string, t DBTrigger) {
+	triggers := append(p.triggers[key], t)
+	sort.Slice(triggers, func(i, j int) bool {
+		return triggers[i].Name < triggers[j].Name
+	})
+	p.triggers[key] = triggers
+}
+
+// removeTrigger removes the trigger name of the table key, returning it.
+func (p *ddlParser) removeTrigger(key, name string) (DBTrigger, bool) {
+	for i, t := range p.triggers[key] {
+		if t.Name == name {
+			p.triggers[key] = append(p.triggers[key][:i:i], p.triggers[key][i+1:]...)
+			return t, true
+		}
+	}
+	return DBTrigger{}, false
+}
+
+func (p *ddlParser) dropTrigger() error {
+	p.accept("IF", "EXISTS")
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("ON"); err != nil {
+		return err
+	}
+	schema, table, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	p.removeTrigger(schema+"."+table, name)
+	return nil
+}
+
+func (p *ddlParser) alterTrigger() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("ON"); err != nil {
+		return err
+	}
+	schema, table, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	if !p.accept("RENAME", "TO") {
+		return nil
+	}
+	newName, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if t, ok := p.removeTrigger(schema+"."+table, name); ok {
+		t.Name = newName
+		p.addTrigger(schema+"."+table, t)
+	}
+	return nil
+}
+
+func (p *ddlParser) tableSecurity(key string) *ddlSecurity {
+	security, ok := p.security[key]
+	if !ok {
+		security = new(ddlSecurity)
+		p.security[key] = security
+	}
+	return security
+}
+
+// createPolicy reads a row level security policy, its expressions as
+// written.
+func (p *ddlParser) createPolicy() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("ON"); err != nil {
+		return err
+	}
+	schema, table, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	policy := DBPolicy{Name: name, Command: "ALL", Roles: []string{"public"}}
+	if p.accept("AS") {
+		switch {
+		case p.accept("RESTRICTIVE"):
+			policy.Restrictive = true
+		case p.accept("PERMISSIVE"):
+		default:
+			return p.errorf("expected PERMISSIVE or RESTRICTIVE")
+		}
+	}
+	if p.accept("FOR") {
+		command := strings.ToUpper(p.next().text)
+		switch command {
+		case "ALL", "SELECT", "INSERT", "UPDATE", "DELETE":
+		default:
+			p.pos--
+			return p.errorf("expected a policy command")
+		}
+		policy.Command = command
+	}
+	if p.accept("TO") {
+		policy.Roles = nil
+		for {
+			role, err := p.ident()
+			if err != nil {
+				return err
+			}
+			policy.Roles = append(policy.Roles, role)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	expr := func() (string, error) {
+		if err := p.expect("("); err != nil {
+			return "", err
+		}
+		tokens := p.skip(func(ddlToken) bool { return false })
+		return joinTokens(tokens), p.expect(")")
+	}
+	if p.accept("USING") {
+		if policy.Using, err = expr(); err != nil {
+			return err
+		}
+	}
+	if p.accept("WITH", "CHECK") {
+		if policy.Check, err = expr(); err != nil {
+			return err
+		}
+	}
+
+	security := p.tableSecurity(schema + "." + table)
+	security.removePolicy(name)
+	security.addPolicy(policy)
+	return nil
+}
+
+func (s *ddlSecurity) addPolicy(policy DBPolicy) {
+	s.Policies = append(s.Policies, policy)
+	sort.Slice(s.Policies, func(i, j int) bool {
+		return s.Policies[i].Name < s.Policies[j].Name
+	})
+}
+
+func (s *ddlSecurity) removePolicy(name string) (DBPolicy, bool) {
+	for i, policy := range s.Policies {
+		if policy.Name == name {
+			s.Policies = append(s.Policies[:i:i], s.Policies[i+1:]...)
+			return policy, true
+		}
+	}
+	return DBPolicy{}, false
+}
+
+func (p *ddlParser) dropPolicy() error {
+	p.accept("IF", "EXISTS")
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("ON"); err != nil {
+		return err
+	}
+	schema, table, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	p.tableSecurity(schema + "." + table).removePolicy(name)
+	return nil
+}
+
+// alterPolicy follows renames, other changes are left out.
+func (p *ddlParser) alterPolicy() error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("ON"); err != nil {
+		return err
+	}
+	schema, table, err := p.tableName()
+	if err != nil {
+		return err
+	}
+	if !p.accept("RENAME", "TO") {
+		return nil
+	}
+	newName, err := p.ident()
+	if err != nil {
+		return err
+	}
+	security := p.tableSecurity(schema + "." + table)
+	if policy, ok := security.removePolicy(name); ok {
+		policy.Name = newName
+		security.addPolicy(policy)
+	}
+	return nil
+}
+
+func (p *ddlParser) comment() error {
+	var target string
+	switch {
+	case p.accept("TABLE"):
+		target = "table"
+	case p.accept("COLUMN"):
+		target = "column"
+	default:
+		return nil
+	}
+	parts, err := p.name()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("IS"); err != nil {
+		return err
+	}
+	var text *string
+	if t := p.next(); t.kind == 's' {
+		text = &t.text
+	} else if !strings.EqualFold(t.text, "NULL") {
+		return p.errorf("expected a comment")
+	}
+
+	if target == "table" {
+		if len(parts) == 1 {
+			parts = append([]string{"public"}, parts...)
+		}
+		key := parts[len(parts)-2] + "." + parts[len(parts)-1]
+		if text == nil {
+			delete(p.comments, key)
+		} else {
+			p.comments[key] = *text
+		}
+		return nil
+	}
+
+	if len(parts) == 2 {
+		parts = append([]string{"public"}, parts...)
+	}
+	if len(parts) < 3 {
+		return p.errorf("expected table.column")
+	}
+	n := len(parts)
+	if t, ok := p.tables[parts[n-3]+"."+parts[n-2]]; ok {
+		if col := t.column(parts[n-1]); col != nil {
+			col.Comment = text
+		}
+	}
+	return nil
+}
+
+// ParseDDL reads a schema from Postgres DDL statements, as written by hand,
+// in migrations or by pg_dump --schema-only. Statements are applied in
+// order, so later ALTER and DROP statements change what earlier ones
+// created. Statements not affecting tables, enums or comments are ignored.
+func ParseDDL(src string) (*Schema, error) {
+	p := newDDLParser()
+	if err := p.parse(src); err != nil {
+		return nil, err
+	}
+	return p.schema(), nil
+}
+
+func (p *ddlParser) parse(src string) error {
+	stmts, err := lexDDL(src)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if err := p.statement(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) schema() *Schema {
+	s := &Schema{
+		Dialect:     "postgres",
+		Tables:      make(DBTables, len(p.tables)),
+		ForeignKeys: p.fks,
+		Comments:    p.comments,
+		Enums:       p.enums,
+		Triggers:    make(DBTriggers),
+		RowSecurity: make(DBRowSecurity),
+	}
+	for _, key := range p.order {
+		t := p.tables[key]
+		if len(t.columns) == 0 {
+			continue
+		}
+		columns := make([]DBColumn, len(t.columns))
+		for i, col := range t.columns {
+			col.OrdinalPosition = i + 1
+			columns[i] = col
+		}
+		s.Tables[key] = columns
+		if triggers := p.triggers[key]; len(triggers) > 0 {
+			s.Triggers[key] = triggers
+		}
+		if security := p.security[key]; security != nil && security.enabled {
+			s.RowSecurity[key] = security.DBTableSecurity
+		}
+	}
+	return s
+}
+
+// ParseDDLFiles parses the .sql files at paths as one script. Directories
+// stand for the .sql files in them, in name order. Files may be gzipped, such
+// as a compressed pg_dump --schema-only artifact.
+func ParseDDLFiles(paths []string) (*Schema, error) {
+	files, err := sqlFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	p := newDDLParser()
+	for _, file := range files {
+		src, err := readSQLFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.parse(src); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return p.schema(), nil
+}
+
+// readSQLFile reads a plain or gzipped SQL file. Dumps in pg_dump's custom
+// format are rejected, they're only readable by pg_restore.
+func readSQLFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+		if data, err = ioutil.ReadAll(zr); err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if bytes.HasPrefix(data, []byte("PGDMP")) {
+		return "", fmt.Errorf("%s is a custom-format dump, convert it with pg_restore --schema-only -f schema.sql %s", path, path)
+	}
+	return string(data), nil
+}
+
+// sqlFiles expands directories among paths to the .sql files in them.
+func sqlFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,328 @@
+package introspect
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// describeColumns describes columns one per line as "name type [not null]
+// [pk] [default ...]".
+func describeColumns(columns []DBColumn) []string {
+	lines := make([]string, len(columns))
+	for i := range columns {
+		col := &columns[i]
+		line := col.ColumnName + " " + columnDef(col)
+		if col.IsPrimaryKey {
+			line += " pk"
+		}
+		if col.ColumnDefault != nil {
+			line += " default " + *col.ColumnDefault
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func TestParseDDLColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		ddl   string
+		table string
+		want  []string
+	}{
+		{
+			name: "create table",
+			ddl: `CREATE TABLE users (
+				id integer PRIMARY KEY,
+				email varchar(255) NOT NULL UNIQUE,
+				name text,
+				price numeric(10,2) DEFAULT 0,
+				tags text[],
+				code text DEFAULT 'A-' || upper(md5(random()::text)),
+				created_at timestamp with time zone NOT NULL DEFAULT now()
+			);`,
+			table: "public.users",
+			want: []string{
+				"id int4 not null pk",
+				"email varchar(255) not null",
+				"name text",
+				"price numeric(10,2) default 0",
+				"tags _text",
+				"code text default 'A-' || upper(md5(random()::text))",
+				"created_at timestamptz not null default now()",
+			},
+		},
+		{
+			name: "table primary key",
+			ddl: `CREATE TABLE IF NOT EXISTS billing.line_items (
+				invoice_id bigint,
+				line int,
+				CONSTRAINT line_items_pkey PRIMARY KEY (invoice_id, line)
+			);`,
+			table: "billing.line_items",
+			want: []string{
+				"invoice_id int8 not null pk",
+				"line int4 not null pk",
+			},
+		},
+		{
+			name:  "serial",
+			ddl:   `CREATE TABLE users (id serial PRIMARY KEY, n bigserial);`,
+			table: "public.users",
+			want: []string{
+				"id int4 not null pk default nextval('users_id_seq'::regclass)",
+				"n int8 not null default nextval('users_n_seq'::regclass)",
+			},
+		},
+		{
+			name:  "serial in a schema",
+			ddl:   `CREATE TABLE billing.users (id serial PRIMARY KEY);`,
+			table: "billing.users",
+			want: []string{
+				"id int4 not null pk default nextval('billing.users_id_seq'::regclass)",
+			},
+		},
+		{
+			name: "identity",
+			ddl: `CREATE TABLE users (
+				id bigint GENERATED ALWAYS AS IDENTITY (START WITH 10) PRIMARY KEY,
+				n int NOT NULL,
+				total numeric GENERATED ALWAYS AS (n * 2) STORED,
+				next int GENERATED ALWAYS AS (n + 1)
+			);
+			ALTER TABLE users ALTER COLUMN n ADD GENERATED BY DEFAULT AS IDENTITY;`,
+			table: "public.users",
+			want: []string{
+				"id int8 not null pk default generated always as identity (sequence name public.users_id_seq)",
+				"n int4 not null default generated by default as identity (sequence name public.users_n_seq)",
+				"total numeric default generated always as (n * 2) stored",
+				"next int4 default generated always as (n + 1) virtual",
+			},
+		},
+		{
+			name: "enum column",
+			ddl: `CREATE TYPE mood AS ENUM ('sad', 'happy');
+				CREATE TABLE people (mood mood NOT NULL);`,
+			table: "public.people",
+			want:  []string{"mood mood not null"},
+		},
+		{
+			name: "alter table",
+			ddl: `CREATE TABLE users (id integer, name text, age int);
+				ALTER TABLE users ADD COLUMN email text NOT NULL;
+				ALTER TABLE users DROP COLUMN age;
+				ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+				ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY (id);`,
+			table: "public.users",
+			want: []string{
+				"id int4 not null pk",
+				"name text not null",
+				"email text not null",
+			},
+		},
+		{
+			name: "pg_dump",
+			ddl: `SET statement_timeout = 0;
+				SET client_encoding = 'UTF8';
+				SELECT pg_catalog.set_config('search_path', '', false);
+
+				CREATE TABLE public.users (
+				    id integer NOT NULL,
+				    name character varying(100)
+				);
+
+				CREATE SEQUENCE public.users_id_seq
+				    AS integer
+				    START WITH 1
+				    INCREMENT BY 1;
+
+				ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+				ALTER TABLE ONLY public.users
+				    ADD CONSTRAINT users_pkey PRIMARY KEY (id);`,
+			table: "public.users",
+			want: []string{
+				"id int4 not null pk default nextval('public.users_id_seq'::regclass)",
+				"name varchar(100)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseDDL(tt.ddl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := describeColumns(s.Tables[tt.table])
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("columns of %s:\ngot  %s\nwant %s", tt.table, strings.Join(got, "\n     "), strings.Join(tt.want, "\n     "))
+			}
+		})
+	}
+}
+
+func TestParseDDLSequenceNames(t *testing.T) {
+	long := strings.Repeat("a", 40)
+	tests := []struct {
+		name string
+		ddl  string
+		// want is the default of the id column of table t
+		want string
+	}{
+		{
+			name: "named in the options",
+			ddl: `CREATE TABLE t (id bigint NOT NULL);
+				ALTER TABLE t ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY (
+				    SEQUENCE NAME billing.t_ids
+				    START WITH 1
+				    CACHE 1
+				);`,
+			want: "generated always as identity (sequence name billing.t_ids)",
+		},
+		{
+			name: "taken by a sequence",
+			ddl: `CREATE SEQUENCE t_id_seq;
+				CREATE TABLE t (id serial);`,
+			want: "nextval('t_id_seq1'::regclass)",
+		},
+		{
+			name: "taken by a renamed table's sequence",
+			ddl: `CREATE TABLE t (id integer GENERATED ALWAYS AS IDENTITY);
+				ALTER TABLE t RENAME TO old_t;
+				CREATE TABLE t (id integer GENERATED ALWAYS AS IDENTITY);`,
+			want: "generated always as identity (sequence name public.t_id_seq1)",
+		},
+		{
+			name: "free again once dropped",
+			ddl: `CREATE SEQUENCE t_id_seq;
+				CREATE TABLE other (id serial);
+				DROP SEQUENCE t_id_seq;
+				CREATE TABLE t (id serial);`,
+			want: "nextval('t_id_seq'::regclass)",
+		},
+		{
+			name: "quoted",
+			ddl:  `CREATE TABLE "T" (id integer GENERATED ALWAYS AS IDENTITY); ALTER TABLE "T" RENAME TO t;`,
+			want: `generated always as identity (sequence name public."T_id_seq")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseDDL(tt.ddl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			for _, col := range s.Tables["public.t"] {
+				if col.ColumnName == "id" && col.ColumnDefault != nil {
+					got = *col.ColumnDefault
+				}
+			}
+			if got != tt.want {
+				t.Errorf("default = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Postgres cuts the longer of table and column name until the name fits
+	s, err := ParseDDL(`CREATE TABLE ` + long + ` (` + long + ` serial);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "nextval('" + long[:29] + "_" + long[:29] + "_seq'::regclass)"
+	if got := *s.Tables["public."+long][0].ColumnDefault; got != want {
+		t.Errorf("default = %q, want %q", got, want)
+	}
+}
+
+func TestParseDDLForeignKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want []DBForeignKey
+	}{
+		{
+			name: "column references",
+			ddl: `CREATE TABLE users (id integer PRIMARY KEY);
+				CREATE TABLE orders (id integer PRIMARY KEY, user_id integer REFERENCES users (id));`,
+			want: []DBForeignKey{
+				{ConstraintName: "orders_user_id_fkey", Schema: "public", TableName: "orders", ColumnName: "user_id", RefSchema: "public", RefTableName: "users", RefColumnName: "id"},
+			},
+		},
+		{
+			name: "alter table add constraint",
+			ddl: `CREATE TABLE billing.accounts (id integer PRIMARY KEY);
+				CREATE TABLE billing.invoices (id integer, account_id integer);
+				ALTER TABLE ONLY billing.invoices
+				    ADD CONSTRAINT invoices_account_fk FOREIGN KEY (account_id) REFERENCES billing.accounts(id) ON DELETE CASCADE;`,
+			want: []DBForeignKey{
+				{ConstraintName: "invoices_account_fk", Schema: "billing", TableName: "invoices", ColumnName: "account_id", RefSchema: "billing", RefTableName: "accounts", RefColumnName: "id"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseDDL(tt.ddl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.ForeignKeys, tt.want) {
+				t.Errorf("foreign keys:\ngot  %+v\nwant %+v", s.ForeignKeys, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDDLComments(t *testing.T) {
+	s, err := ParseDDL(`CREATE TABLE users (id integer, name text);
+		COMMENT ON TABLE users IS 'People who sign in';
+		COMMENT ON COLUMN public.users.name IS 'Full name, it''s shown @sensitive';
+		COMMENT ON COLUMN users.id IS 'dropped';
+		COMMENT ON COLUMN users.id IS NULL;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Comments["public.users"]; got != "People who sign in" {
+		t.Errorf("table comment = %q", got)
+	}
+	columns := s.Tables["public.users"]
+	if columns[0].Comment != nil {
+		t.Errorf("id comment = %q, want none", *columns[0].Comment)
+	}
+	if got := columns[1].Comment; got == nil || *got != "Full name, it's shown @sensitive" {
+		t.Errorf("name comment = %v", got)
+	}
+}
+
+func TestParseDDLEnums(t *testing.T) {
+	s, err := ParseDDL(`CREATE TYPE mood AS ENUM ('sad', 'ok');
+		CREATE TYPE public.status AS ENUM ('draft');
+		ALTER TYPE mood ADD VALUE 'happy';
+		ALTER TYPE mood ADD VALUE IF NOT EXISTS 'meh' BEFORE 'ok';
+		CREATE TYPE gone AS ENUM ('x');
+		DROP TYPE gone;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DBEnums{
+		"mood":   {"sad", "meh", "ok", "happy"},
+		"status": {"draft"},
+	}
+	if !reflect.DeepEqual(s.Enums, want) {
+		t.Errorf("enums = %v, want %v", s.Enums, want)
+	}
+}
+
+func TestParseDDLErrors(t *testing.T) {
+	tests := []string{
+		`CREATE TABLE users (id integer`,
+		`CREATE TABLE users (id integer, name text) ; COMMENT ON TABLE users IS 42;`,
+		`CREATE TABLE 'users' (id integer);`,
+	}
+	for _, ddl := range tests {
+		if _, err := ParseDDL(ddl); err == nil {
+			t.Errorf("ParseDDL(%q): no error", ddl)
+		}
+	}
+}
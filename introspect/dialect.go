@@ -0,0 +1,181 @@
+package introspect
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// Dialect runs the introspection queries of one kind of database, the
+// Introspector methods of the same names describe their results. Schemas
+// are databases in dialects without schemas.
+type Dialect interface {
+	Name() string
+	Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error)
+	ForeignKeys(ctx context.Context, db *sql.DB, schemas []string) ([]DBForeignKey, error)
+	TableComments(ctx context.Context, db *sql.DB, schemas []string) (map[string]string, error)
+	Triggers(ctx context.Context, db *sql.DB, schemas []string) (DBTriggers, error)
+	RowSecurity(ctx context.Context, db *sql.DB, schemas []string) (DBRowSecurity, error)
+	Schemas(ctx context.Context, db *sql.DB) ([]string, error)
+	Enums(ctx context.Context, db *sql.DB, schemas []string) (DBEnums, error)
+	SchemaHash(ctx context.Context, db *sql.DB, schemas []string) (string, error)
+	// Types returns the built-in mapping of the dialect's column types.
+	Types() *typemap.TypesMapping
+}
+
+// Dialects are the dialects by name.
+var Dialects = map[string]Dialect{
+	"postgres":  Postgres,
+	"mysql":     MySQL,
+	"sqlite":    SQLite,
+	"cockroach": Cockroach,
+	"mssql":     MSSQL,
+}
+
+// queryColumns reads the columns of a tables query, selected in the order of
+// the DBColumn fields.
+func queryColumns(ctx context.Context, db *sql.DB, q string, args ...interface{}) (DBTables, error) {
+	tables := make(DBTables)
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, &Error{Op: "query", Object: "columns", Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		col := new(DBColumn)
+		if err := rows.Scan(
+			&col.TableSchema, &col.TableName, &col.ColumnName, &col.OrdinalPosition, &col.ColumnDefault, &col.IsNullable, &col.DataType,
+			&col.UDTName, &col.CharacterMaximumLength, &col.CharacterOctetLength, &col.NumericPrecision, &col.NumericScale,
+			&col.Comment, &col.IsPrimaryKey,
+		); err != nil {
+			return nil, &Error{Op: "scan", Object: "columns", Err: err}
+		}
+		key := col.TableSchema + "." + col.TableName
+		if _, ok := tables[key]; !ok {
+			tables[key] = make([]DBColumn, 0, 1)
+			report.Steps.Add(1)
+		}
+		tables[key] = append(tables[key], *col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &Error{Op: "scan", Object: "columns", Err: err}
+	}
+	return tables, nil
+}
+
+// queryForeignKeys reads the foreign key columns of q, selected in the order
+// of the DBForeignKey fields.
+func queryForeignKeys(ctx context.Context, db *sql.DB, q string, args ...interface{}) ([]DBForeignKey, error) {
+	var fks []DBForeignKey
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, &Error{Op: "query", Object: "foreign keys", Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fk DBForeignKey
+		if err := rows.Scan(
+			&fk.ConstraintName, &fk.Schema, &fk.TableName, &fk.ColumnName,
+			&fk.RefSchema, &fk.RefTableName, &fk.RefColumnName,
+		); err != nil {
+			return nil, &Error{Op: "scan", Object: "foreign keys", Err: err}
+		}
+		fks = append(fks, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &Error{Op: "scan", Object: "foreign keys", Err: err}
+	}
+	return fks, nil
+}
+
+// queryPairs reads the two string columns of q.
+func queryPairs(ctx context.Context, db *sql.DB, object, q string, args ...interface{}) ([][2]string, error) {
+	var pairs [][2]string
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, &Error{Op: "query", Object: object, Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pair [2]string
+		if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+			return nil, &Error{Op: "scan", Object: object, Err: err}
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &Error{Op: "scan", Object: object, Err: err}
+	}
+	return pairs, nil
+}
+
+// queryComments reads "schema.table" and comment pairs.
+func queryComments(ctx context.Context, db *sql.DB, q string, args ...interface{}) (map[string]string, error) {
+	pairs, err := queryPairs(ctx, db, "table comments", q, args...)
+	if err != nil {
+		return nil, err
+	}
+	comments := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		comments[p[0]] = p[1]
+	}
+	return comments, nil
+}
+
+// queryEnums reads type name and label pairs, labels in sort order.
+func queryEnums(ctx context.Context, db *sql.DB, q string, args ...interface{}) (DBEnums, error) {
+	pairs, err := queryPairs(ctx, db, "enums", q, args...)
+	if err != nil {
+		return nil, err
+	}
+	enums := make(DBEnums)
+	for _, p := range pairs {
+		enums[p[0]] = append(enums[p[0]], p[1])
+	}
+	return enums, nil
+}
+
+// queryStrings reads the single string column of q.
+func queryStrings(ctx context.Context, db *sql.DB, object, q string, args ...interface{}) ([]string, error) {
+	var values []string
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, &Error{Op: "query", Object: object, Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, &Error{Op: "scan", Object: object, Err: err}
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &Error{Op: "scan", Object: object, Err: err}
+	}
+	return values, nil
+}
+
+// queryHash digests the rows of q, for databases that can't aggregate them
+// into a hash themselves.
+func queryHash(ctx context.Context, db *sql.DB, q string, args ...interface{}) (string, error) {
+	items, err := queryStrings(ctx, db, "schema hash", q, args...)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum([]byte(strings.Join(items, "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
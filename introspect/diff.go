@@ -0,0 +1,252 @@
+package introspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaDiff lists what changed between two schemas, tables and their
+// columns sorted by name.
+type SchemaDiff struct {
+	Tables []TableDiff `json:"tables"`
+}
+
+// TableDiff is an "added", "removed" or "changed" table.
+type TableDiff struct {
+	Table   string       `json:"table"`
+	Change  string       `json:"change"`
+	Columns []ColumnDiff `json:"columns,omitempty"`
+}
+
+// ColumnDiff is an "added", "removed" or "changed" column. Fields name what
+// changed about a changed column.
+type ColumnDiff struct {
+	Column string    `json:"column"`
+	Change string    `json:"change"`
+	Fields []string  `json:"fields,omitempty"`
+	Old    *DBColumn `json:"old,omitempty"`
+	New    *DBColumn `json:"new,omitempty"`
+}
+
+// Empty reports whether the schemas are the same.
+func (d SchemaDiff) Empty() bool {
+	return len(d.Tables) == 0
+}
+
+// DiffSchemas compares the tables and columns of old and new.
+func DiffSchemas(old, new *Schema) SchemaDiff {
+	keys := make(map[string]bool)
+	for key := range old.Tables {
+		keys[key] = true
+	}
+	for key := range new.Tables {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	d := SchemaDiff{Tables: []TableDiff{}}
+	for _, key := range sorted {
+		before, after := old.Tables[key], new.Tables[key]
+		switch {
+		case before == nil:
+			d.Tables = append(d.Tables, TableDiff{Table: key, Change: "added", Columns: columnChanges(nil, after)})
+		case after == nil:
+			d.Tables = append(d.Tables, TableDiff{Table: key, Change: "removed", Columns: columnChanges(before, nil)})
+		default:
+			if columns := columnChanges(before, after); len(columns) > 0 {
+				d.Tables = append(d.Tables, TableDiff{Table: key, Change: "changed", Columns: columns})
+			}
+		}
+	}
+	return d
+}
+
+func columnChanges(old, new []DBColumn) []ColumnDiff {
+	byName := func(columns []DBColumn) map[string]*DBColumn {
+		m := make(map[string]*DBColumn, len(columns))
+		for i := range columns {
+			m[columns[i].ColumnName] = &columns[i]
+		}
+		return m
+	}
+	before, after := byName(old), byName(new)
+
+	var diffs []ColumnDiff
+	for _, col := range old {
+		if after[col.ColumnName] == nil {
+			diffs = append(diffs, ColumnDiff{Column: col.ColumnName, Change: "removed", Old: before[col.ColumnName]})
+		}
+	}
+	for _, col := range new {
+		o, n := before[col.ColumnName], after[col.ColumnName]
+		if o == nil {
+			diffs = append(diffs, ColumnDiff{Column: col.ColumnName, Change: "added", New: n})
+			continue
+		}
+		if fields := changedFields(o, n); len(fields) > 0 {
+			diffs = append(diffs, ColumnDiff{Column: col.ColumnName, Change: "changed", Fields: fields, Old: o, New: n})
+		}
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Column < diffs[j].Column
+	})
+	return diffs
+}
+
+// changedFields compares what generated code depends on, the position of a
+// column doesn't count.
+func changedFields(old, new *DBColumn) []string {
+	var fields []string
+	if old.UDTName != new.UDTName || !equalInt(old.CharacterMaximumLength, new.CharacterMaximumLength) || !equalInt(old.NumericPrecision, new.NumericPrecision) || !equalInt(old.NumericScale, new.NumericScale) {
+		fields = append(fields, "type")
+	}
+	if old.IsNullable != new.IsNullable {
+		fields = append(fields, "nullable")
+	}
+	if !equalString(old.ColumnDefault, new.ColumnDefault) {
+		fields = append(fields, "default")
+	}
+	if old.IsPrimaryKey != new.IsPrimaryKey {
+		fields = append(fields, "primary key")
+	}
+	if !equalString(old.Comment, new.Comment) {
+		fields = append(fields, "comment")
+	}
+	return fields
+}
+
+func equalInt(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func equalString(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+// columnType describes the type of col like DDL does.
+func columnType(col *DBColumn) string {
+	t := col.UDTName
+	switch {
+	case col.CharacterMaximumLength != nil:
+		t += fmt.Sprintf("(%d)", *col.CharacterMaximumLength)
+	case col.NumericPrecision != nil && col.NumericScale != nil && col.UDTName == "numeric":
+		t += fmt.Sprintf("(%d,%d)", *col.NumericPrecision, *col.NumericScale)
+	case col.NumericPrecision != nil && col.UDTName == "numeric":
+		t += fmt.Sprintf("(%d)", *col.NumericPrecision)
+	}
+	return t
+}
+
+// columnDef describes col's type and nullability.
+func columnDef(col *DBColumn) string {
+	if col.IsNullable {
+		return columnType(col)
+	}
+	return columnType(col) + " not null"
+}
+
+func describeField(col *DBColumn, field string) string {
+	switch field {
+	case "type":
+		return columnType(col)
+	case "nullable":
+		if col.IsNullable {
+			return "null"
+		}
+		return "not null"
+	case "default":
+		if col.ColumnDefault == nil {
+			return "none"
+		}
+		return *col.ColumnDefault
+	case "primary key":
+		return fmt.Sprint(col.IsPrimaryKey)
+	case "comment":
+		if col.Comment == nil {
+			return "none"
+		}
+		return fmt.Sprintf("%q", *col.Comment)
+	}
+	return ""
+}
+
+// WriteText writes d for people: a line per table marked +, - or ~ and a
+// line per column below it.
+func (d SchemaDiff) WriteText(w io.Writer) error {
+	marks := map[string]string{"added": "+", "removed": "-", "changed": "~"}
+	var b strings.Builder
+	for _, t := range d.Tables {
+		fmt.Fprintf(&b, "%s %s\n", marks[t.Change], t.Table)
+		for _, c := range t.Columns {
+			switch c.Change {
+			case "added":
+				fmt.Fprintf(&b, "    + %s %s\n", c.Column, columnDef(c.New))
+			case "removed":
+				fmt.Fprintf(&b, "    - %s %s\n", c.Column, columnDef(c.Old))
+			default:
+				changes := make([]string, len(c.Fields))
+				for i, field := range c.Fields {
+					changes[i] = fmt.Sprintf("%s %s -> %s", field, describeField(c.Old, field), describeField(c.New, field))
+				}
+				fmt.Fprintf(&b, "    ~ %s: %s\n", c.Column, strings.Join(changes, ", "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ChangedTables returns the keys of the tables of new whose models may differ
+// from the ones generated from old: new tables and tables whose columns,
+// comment, triggers or row level security changed, or whose foreign keys,
+// from or to them, did. Enums are types of their own in generated code, so a
+// change to any enum changes every table.
+func ChangedTables(old, new *Schema) map[string]bool {
+	enumsChanged := !reflect.DeepEqual(old.Enums, new.Enums)
+	changed := make(map[string]bool)
+	for key := range new.Tables {
+		if enumsChanged || !bytes.Equal(tableSignature(old, key), tableSignature(new, key)) {
+			changed[key] = true
+		}
+	}
+	return changed
+}
+
+// tableSignature encodes what the model of table key depends on.
+func tableSignature(s *Schema, key string) []byte {
+	columns, ok := s.Tables[key]
+	if !ok {
+		return nil
+	}
+	columns = append([]DBColumn(nil), columns...)
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+	})
+	var fks []DBForeignKey
+	for _, fk := range s.ForeignKeys {
+		if fk.Schema+"."+fk.TableName == key || fk.RefSchema+"."+fk.RefTableName == key {
+			fks = append(fks, fk)
+		}
+	}
+	var security *DBTableSecurity
+	if rls, ok := s.RowSecurity[key]; ok {
+		security = &rls
+	}
+	data, _ := json.Marshal(struct {
+		Columns     []DBColumn
+		Comment     string
+		ForeignKeys []DBForeignKey
+		Triggers    []DBTrigger
+		RowSecurity *DBTableSecurity
+	}{columns, s.Comments[key], fks, s.Triggers[key], security})
+	return data
+}
@@ -0,0 +1,169 @@
+package introspect
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func mustParseDDL(t *testing.T, ddl string) *Schema {
+	t.Helper()
+	s, err := ParseDDL(ddl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDiffSchemas(t *testing.T) {
+	base := `CREATE TABLE users (id integer PRIMARY KEY, name text);
+		CREATE TABLE orders (id integer PRIMARY KEY);`
+	tests := []struct {
+		name string
+		new  string
+		// want describes each change as "table change" or
+		// "table.column change [fields]".
+		want []string
+	}{
+		{
+			name: "same",
+			new:  base,
+			want: nil,
+		},
+		{
+			name: "column order doesn't count",
+			new: `CREATE TABLE users (name text, id integer PRIMARY KEY);
+				CREATE TABLE orders (id integer PRIMARY KEY);`,
+			want: nil,
+		},
+		{
+			name: "tables added and removed",
+			new: `CREATE TABLE users (id integer PRIMARY KEY, name text);
+				CREATE TABLE items (id integer);`,
+			want: []string{
+				"public.items added",
+				"public.items.id added",
+				"public.orders removed",
+				"public.orders.id removed",
+			},
+		},
+		{
+			name: "columns changed",
+			new: `CREATE TABLE users (id bigint PRIMARY KEY, name text NOT NULL DEFAULT '', email text);
+				CREATE TABLE orders (id integer);
+				COMMENT ON COLUMN orders.id IS 'number';`,
+			want: []string{
+				"public.orders changed",
+				"public.orders.id changed [nullable primary key comment]",
+				"public.users changed",
+				"public.users.email added",
+				"public.users.id changed [type]",
+				"public.users.name changed [nullable default]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffSchemas(mustParseDDL(t, base), mustParseDDL(t, tt.new))
+			var got []string
+			for _, table := range d.Tables {
+				got = append(got, table.Table+" "+table.Change)
+				for _, col := range table.Columns {
+					line := table.Table + "." + col.Column + " " + col.Change
+					if len(col.Fields) > 0 {
+						line += " " + fmt.Sprint(col.Fields)
+					}
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+			if d.Empty() != (len(tt.want) == 0) {
+				t.Errorf("Empty() = %v", d.Empty())
+			}
+		})
+	}
+}
+
+func TestChangedTables(t *testing.T) {
+	base := `CREATE TYPE mood AS ENUM ('sad', 'happy');
+		CREATE TABLE users (id integer PRIMARY KEY, name text);
+		CREATE TABLE orders (id integer PRIMARY KEY, user_id integer);
+		CREATE TABLE notes (id integer PRIMARY KEY);`
+	tests := []struct {
+		name  string
+		extra string
+		want  []string
+	}{
+		{
+			name: "nothing",
+			want: nil,
+		},
+		{
+			name:  "new table",
+			extra: `CREATE TABLE items (id integer);`,
+			want:  []string{"public.items"},
+		},
+		{
+			name:  "column",
+			extra: `ALTER TABLE users ADD COLUMN email text;`,
+			want:  []string{"public.users"},
+		},
+		{
+			name:  "table comment",
+			extra: `COMMENT ON TABLE notes IS 'Free text';`,
+			want:  []string{"public.notes"},
+		},
+		{
+			name:  "foreign key changes both ends",
+			extra: `ALTER TABLE orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES users (id);`,
+			want:  []string{"public.orders", "public.users"},
+		},
+		{
+			name:  "trigger",
+			extra: `CREATE TRIGGER notes_touch BEFORE UPDATE ON notes FOR EACH ROW EXECUTE FUNCTION touch();`,
+			want:  []string{"public.notes"},
+		},
+		{
+			name:  "row level security",
+			extra: `ALTER TABLE notes ENABLE ROW LEVEL SECURITY;`,
+			want:  []string{"public.notes"},
+		},
+		{
+			name: "policy",
+			extra: `ALTER TABLE notes ENABLE ROW LEVEL SECURITY;
+				CREATE POLICY own ON notes USING (true);`,
+			want: []string{"public.notes"},
+		},
+		{
+			name:  "enum changes every table",
+			extra: `ALTER TYPE mood ADD VALUE 'ok';`,
+			want:  []string{"public.notes", "public.orders", "public.users"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := ChangedTables(mustParseDDL(t, base), mustParseDDL(t, base+"\n"+tt.extra))
+			var got []string
+			for key := range changed {
+				got = append(got, key)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedTablesRenamedPolicy(t *testing.T) {
+	base := `CREATE TABLE notes (id integer PRIMARY KEY);
+		ALTER TABLE notes ENABLE ROW LEVEL SECURITY;
+		CREATE POLICY own ON notes USING (true);`
+	changed := ChangedTables(mustParseDDL(t, base), mustParseDDL(t, base+"\nALTER POLICY own ON notes RENAME TO mine;"))
+	if !changed["public.notes"] {
+		t.Errorf("changed = %v, want public.notes", changed)
+	}
+}
@@ -0,0 +1,28 @@
+package introspect
+
+import (
+	"fmt"
+)
+
+// Error is the error of a failed introspection. Op is "connect", "query" or
+// "scan", Object names what was being read. A done context shows up as an
+// Error wrapping context.Canceled or context.DeadlineExceeded.
+type Error struct {
+	Op     string
+	Object string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	switch e.Op {
+	case "connect":
+		return fmt.Sprintf("cannot connect to database: %v", e.Err)
+	case "scan":
+		return fmt.Sprintf("reading %s: %v", e.Object, e.Err)
+	}
+	return fmt.Sprintf("querying %s: %v", e.Object, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
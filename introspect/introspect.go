@@ -0,0 +1,260 @@
+// Package introspect reads tables, foreign keys, enums and comments from the
+// information schema and catalogs of a database, Postgres or another Dialect.
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+type DBTables map[string][]DBColumn
+
+type DBColumn struct {
+	TableSchema     string  `json:"table_schema" yaml:"table_schema"`
+	TableName       string  `json:"table_name" yaml:"table_name"`
+	ColumnName      string  `json:"column_name" yaml:"column_name"`
+	OrdinalPosition int     `json:"ordinal_position" yaml:"ordinal_position"`
+	ColumnDefault   *string `json:"column_default,omitempty" yaml:"column_default,omitempty"`
+	IsNullable      bool    `json:"is_nullable" yaml:"is_nullable"`
+	DataType        string  `json:"data_type" yaml:"data_type"`
+	UDTName         string  `json:"udt_name" yaml:"udt_name"`
+	// CharacterMaximumLength is also the dimensions of pgvector vectors.
+	CharacterMaximumLength *int    `json:"character_maximum_length,omitempty" yaml:"character_maximum_length,omitempty"`
+	CharacterOctetLength   *int    `json:"character_octet_length,omitempty" yaml:"character_octet_length,omitempty"`
+	NumericPrecision       *int    `json:"numeric_precision,omitempty" yaml:"numeric_precision,omitempty"`
+	NumericScale           *int    `json:"numeric_scale,omitempty" yaml:"numeric_scale,omitempty"`
+	Comment                *string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	IsPrimaryKey           bool    `json:"is_primary_key" yaml:"is_primary_key"`
+}
+
+// Filter keeps the tables matching any include pattern (all tables if there
+// are none) and none of the exclude patterns. Patterns are path.Match globs
+// matched against both "table" and "schema.table".
+func (tables DBTables) Filter(include, exclude []string) DBTables {
+	filtered := make(DBTables, len(tables))
+	for key, columns := range tables {
+		if TableIncluded(columns[0].TableSchema, columns[0].TableName, include, exclude) {
+			filtered[key] = columns
+		} else {
+			report.Debug.Printf("skipping table %s: filtered out", key)
+		}
+	}
+	return filtered
+}
+
+// TableIncluded reports whether schema.table passes the include and exclude
+// patterns, see Filter.
+func TableIncluded(schema, table string, include, exclude []string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, table); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, schema+"."+table); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(include) == 0 || matches(include)) && !matches(exclude)
+}
+
+// Marked reports whether comment carries marker as a word of its own, maybe
+// followed by punctuation, so that tables can be left out from the schema,
+// e.g. COMMENT ON TABLE x IS '@nogen'. No comment is marked by an empty marker.
+func Marked(comment, marker string) bool {
+	if marker == "" {
+		return false
+	}
+	for _, word := range strings.Fields(comment) {
+		if strings.TrimRight(word, ".,:;") == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipMarked drops the tables whose comment, keyed "schema.table" in comments,
+// is Marked.
+func (tables DBTables) SkipMarked(comments map[string]string, marker string) DBTables {
+	kept := make(DBTables, len(tables))
+	for key, columns := range tables {
+		if Marked(comments[key], marker) {
+			report.Debug.Printf("skipping table %s: commented %s", key, marker)
+		} else {
+			kept[key] = columns
+		}
+	}
+	return kept
+}
+
+type DBForeignKey struct {
+	ConstraintName string `json:"constraint_name" yaml:"constraint_name"`
+	Schema         string `json:"schema" yaml:"schema"`
+	TableName      string `json:"table_name" yaml:"table_name"`
+	ColumnName     string `json:"column_name" yaml:"column_name"`
+	RefSchema      string `json:"ref_schema" yaml:"ref_schema"`
+	RefTableName   string `json:"ref_table_name" yaml:"ref_table_name"`
+	RefColumnName  string `json:"ref_column_name" yaml:"ref_column_name"`
+}
+
+// DBEnums maps enum type names to their labels in sort order.
+type DBEnums map[string][]string
+
+// DBTriggers maps "schema.table" to the triggers of the table in name order.
+type DBTriggers map[string][]DBTrigger
+
+type DBTrigger struct {
+	Name string `json:"name" yaml:"name"`
+	// Timing is BEFORE, AFTER or INSTEAD OF.
+	Timing string   `json:"timing" yaml:"timing"`
+	Events []string `json:"events" yaml:"events"`
+	// Level is ROW or STATEMENT.
+	Level    string `json:"level" yaml:"level"`
+	Function string `json:"function" yaml:"function"`
+}
+
+// String describes t the way CREATE TRIGGER does.
+func (t DBTrigger) String() string {
+	return fmt.Sprintf("%s %s %s FOR EACH %s EXECUTE FUNCTION %s()", t.Name, t.Timing, strings.Join(t.Events, " OR "), t.Level, t.Function)
+}
+
+// DBRowSecurity maps "schema.table" of the tables with row level security
+// enabled to their policies.
+type DBRowSecurity map[string]DBTableSecurity
+
+type DBTableSecurity struct {
+	// Forced applies the policies to the table owner too.
+	Forced bool `json:"forced,omitempty" yaml:"forced,omitempty"`
+	// Policies are in name order. Without any no rows are visible.
+	Policies []DBPolicy `json:"policies" yaml:"policies"`
+}
+
+type DBPolicy struct {
+	Name        string `json:"name" yaml:"name"`
+	Restrictive bool   `json:"restrictive,omitempty" yaml:"restrictive,omitempty"`
+	// Command is ALL, SELECT, INSERT, UPDATE or DELETE.
+	Command string   `json:"command" yaml:"command"`
+	Roles   []string `json:"roles" yaml:"roles"`
+	// Using and Check are the USING and WITH CHECK expressions.
+	Using string `json:"using,omitempty" yaml:"using,omitempty"`
+	Check string `json:"check,omitempty" yaml:"check,omitempty"`
+}
+
+// String describes p the way CREATE POLICY does.
+func (p DBPolicy) String() string {
+	s := p.Name
+	if p.Restrictive {
+		s += " AS RESTRICTIVE"
+	}
+	s += fmt.Sprintf(" FOR %s TO %s", p.Command, strings.Join(p.Roles, ", "))
+	if p.Using != "" {
+		s += " USING (" + p.Using + ")"
+	}
+	if p.Check != "" {
+		s += " WITH CHECK (" + p.Check + ")"
+	}
+	return s
+}
+
+// Introspector reads the schema of a database. Its methods stop early when
+// their context is done and fail with an *Error.
+type Introspector struct {
+	db      *sql.DB
+	dialect Dialect
+	owned   bool
+}
+
+// New returns an Introspector using db, a connection pool to a database of
+// dialect. For Postgres that's lib/pq or pgx, a pgx pool configured
+// natively, e.g. with a custom dialer, is passed as stdlib.OpenDB(connConfig).
+// Close doesn't close db then, it belongs to the caller.
+func New(db *sql.DB, dialect Dialect) *Introspector {
+	return &Introspector{db: db, dialect: dialect}
+}
+
+// Open connects to the database of connStr using driver, e.g. "postgres"
+// for lib/pq, "pgx" or "mysql". For Postgres connStr may be a key=value
+// connection string or a postgres:// URL.
+func Open(ctx context.Context, dialect Dialect, driver, connStr string) (*Introspector, error) {
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		return nil, &Error{Op: "connect", Err: err}
+	}
+	// sql.Open doesn't connect, fail here rather than on the first query
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, &Error{Op: "connect", Err: err}
+	}
+	return &Introspector{db: db, dialect: dialect, owned: true}, nil
+}
+
+// Close closes the connection pool opened by Open.
+func (i *Introspector) Close() error {
+	if !i.owned {
+		return nil
+	}
+	return i.db.Close()
+}
+
+// Dialect returns the dialect of the database.
+func (i *Introspector) Dialect() Dialect {
+	return i.dialect
+}
+
+// Tables returns the columns of all base tables in schemas, keyed by
+// "schema.table".
+func (i *Introspector) Tables(ctx context.Context, schemas []string) (DBTables, error) {
+	return i.dialect.Tables(ctx, i.db, schemas)
+}
+
+// ForeignKeys returns a row per column of every foreign key in schemas.
+func (i *Introspector) ForeignKeys(ctx context.Context, schemas []string) ([]DBForeignKey, error) {
+	return i.dialect.ForeignKeys(ctx, i.db, schemas)
+}
+
+// TableComments returns table comments keyed by "schema.table".
+func (i *Introspector) TableComments(ctx context.Context, schemas []string) (map[string]string, error) {
+	return i.dialect.TableComments(ctx, i.db, schemas)
+}
+
+// Triggers returns the triggers of the tables in schemas, leaving out the
+// internal ones enforcing foreign keys.
+func (i *Introspector) Triggers(ctx context.Context, schemas []string) (DBTriggers, error) {
+	return i.dialect.Triggers(ctx, i.db, schemas)
+}
+
+// RowSecurity returns the tables of schemas with row level security enabled
+// and their policies.
+func (i *Introspector) RowSecurity(ctx context.Context, schemas []string) (DBRowSecurity, error) {
+	return i.dialect.RowSecurity(ctx, i.db, schemas)
+}
+
+// Schemas returns the schemas holding at least one table, leaving out the
+// system ones.
+func (i *Introspector) Schemas(ctx context.Context) ([]string, error) {
+	return i.dialect.Schemas(ctx, i.db)
+}
+
+// Enums returns the enum types of schemas.
+func (i *Introspector) Enums(ctx context.Context, schemas []string) (DBEnums, error) {
+	return i.dialect.Enums(ctx, i.db, schemas)
+}
+
+// SchemaHash digests everything generated code depends on: columns, their
+// types and defaults, constraints, enum labels and comments. It changes
+// whenever a migration touches the schemas.
+func (i *Introspector) SchemaHash(ctx context.Context, schemas []string) (string, error) {
+	return i.dialect.SchemaHash(ctx, i.db, schemas)
+}
+
+// Types returns the built-in type mapping of the database's dialect.
+func (i *Introspector) Types() *typemap.TypesMapping {
+	return i.dialect.Types()
+}
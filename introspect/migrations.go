@@ -0,0 +1,91 @@
+package introspect
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParseMigrations reads the schema the migrations in dir result in, by
+// parsing their up migrations in version order. It understands the layouts
+// of golang-migrate (1_init.up.sql next to 1_init.down.sql), goose and dbmate
+// (up and down sections in one file) and atlas (plain versioned files).
+func ParseMigrations(dir string) (*Schema, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .sql migrations in %s", dir)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		vi, vj := migrationVersion(files[i]), migrationVersion(files[j])
+		if len(vi) != len(vj) {
+			return len(vi) < len(vj)
+		}
+		if vi != vj {
+			return vi < vj
+		}
+		return files[i] < files[j]
+	})
+
+	p := newDDLParser()
+	for _, file := range files {
+		if strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.parse(upMigration(string(src))); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return p.schema(), nil
+}
+
+// migrationVersion returns the leading digits of a migration's file name,
+// without leading zeros so that versions compare by length first.
+func migrationVersion(path string) string {
+	name := filepath.Base(path)
+	end := 0
+	for end < len(name) && isDigit(name[end]) {
+		end++
+	}
+	return strings.TrimLeft(name[:end], "0")
+}
+
+// upMigration returns the up section of a goose or dbmate migration, other
+// migrations are all up.
+func upMigration(src string) string {
+	var (
+		b        strings.Builder
+		sections bool
+		up       bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	scanner.Buffer(nil, len(src)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.Join(strings.Fields(line), " ") {
+		case "-- +goose Up", "-- migrate:up":
+			sections, up = true, true
+		case "-- +goose Down", "-- migrate:down":
+			sections, up = true, false
+		}
+		// lines are kept blank rather than dropped so that errors point to
+		// the right line
+		if up {
+			b.WriteString(line)
+		}
+		b.WriteByte('\n')
+	}
+	if !sections {
+		return src
+	}
+	return b.String()
+}
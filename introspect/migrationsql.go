@@ -0,0 +1,218 @@
+package introspect
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// plainIdent matches the identifiers DDL can leave unquoted.
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// reservedIdents need quoting although they look plain.
+var reservedIdents = map[string]bool{
+	"all": true, "check": true, "column": true, "constraint": true, "default": true, "desc": true,
+	"from": true, "group": true, "limit": true, "order": true, "primary": true, "references": true,
+	"select": true, "table": true, "to": true, "user": true, "where": true,
+}
+
+func quoteIdent(name string) string {
+	if plainIdent.MatchString(name) && !reservedIdents[name] {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteTable(key string) string {
+	parts := strings.SplitN(key, ".", 2)
+	return quoteIdent(parts[0]) + "." + quoteIdent(parts[1])
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// sqlType renders the type of col for DDL, array udt names start with _.
+func sqlType(col *DBColumn) string {
+	if strings.HasPrefix(col.UDTName, "_") {
+		c := *col
+		c.UDTName = col.UDTName[1:]
+		return sqlType(&c) + "[]"
+	}
+	return columnType(col)
+}
+
+// columnSQL renders col as in CREATE TABLE or ADD COLUMN.
+func columnSQL(col *DBColumn) string {
+	def := quoteIdent(col.ColumnName) + " " + sqlType(col)
+	if d := col.ColumnDefault; d != nil {
+		// identity columns of parsed DDL carry their clause as the default
+		if strings.HasPrefix(strings.ToLower(*d), "generated") {
+			def += " " + strings.ToUpper((*d)[:len("generated")]) + (*d)[len("generated"):]
+		} else {
+			def += " DEFAULT " + *d
+		}
+	}
+	if !col.IsNullable {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+func createTableSQL(key string, columns []DBColumn) []string {
+	var (
+		defs []string
+		pk   []string
+	)
+	for i := range columns {
+		defs = append(defs, "    "+columnSQL(&columns[i]))
+		if columns[i].IsPrimaryKey {
+			pk = append(pk, quoteIdent(columns[i].ColumnName))
+		}
+	}
+	if len(pk) > 0 {
+		defs = append(defs, "    PRIMARY KEY ("+strings.Join(pk, ", ")+")")
+	}
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (\n%s\n);", quoteTable(key), strings.Join(defs, ",\n"))}
+	for i := range columns {
+		if c := columns[i].Comment; c != nil {
+			stmts = append(stmts, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", quoteTable(key), quoteIdent(columns[i].ColumnName), quoteLiteral(*c)))
+		}
+	}
+	return stmts
+}
+
+// alterColumnSQL changes the column from to to, field by field.
+func alterColumnSQL(key string, from, to *DBColumn, fields []string) []string {
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", quoteTable(key), quoteIdent(to.ColumnName))
+	var stmts []string
+	for _, field := range fields {
+		switch field {
+		case "type":
+			stmts = append(stmts, fmt.Sprintf("%s TYPE %s USING %s::%s;", alter, sqlType(to), quoteIdent(to.ColumnName), sqlType(to)))
+		case "nullable":
+			if to.IsNullable {
+				stmts = append(stmts, alter+" DROP NOT NULL;")
+			} else {
+				stmts = append(stmts, alter+" SET NOT NULL;")
+			}
+		case "default":
+			if to.ColumnDefault == nil {
+				stmts = append(stmts, alter+" DROP DEFAULT;")
+			} else {
+				stmts = append(stmts, alter+" SET DEFAULT "+*to.ColumnDefault+";")
+			}
+		case "primary key":
+			stmts = append(stmts, fmt.Sprintf("-- TODO: the primary key of %s changed, %s is part of it: %t", key, to.ColumnName, to.IsPrimaryKey))
+		case "comment":
+			comment := "NULL"
+			if to.Comment != nil {
+				comment = quoteLiteral(*to.Comment)
+			}
+			stmts = append(stmts, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", quoteTable(key), quoteIdent(to.ColumnName), comment))
+		}
+	}
+	return stmts
+}
+
+// MigrationSQL returns Postgres statements taking the old schema of d to the
+// new one (up) and back (down). They're a starting point: renames show up as
+// a drop and an add, and data is neither kept nor converted beyond a cast.
+func (d SchemaDiff) MigrationSQL() (up, down []string) {
+	for _, t := range d.Tables {
+		switch t.Change {
+		case "added":
+			columns := tableColumns(t, func(c ColumnDiff) *DBColumn { return c.New })
+			up = append(up, createTableSQL(t.Table, columns)...)
+			down = append(down, fmt.Sprintf("DROP TABLE %s;", quoteTable(t.Table)))
+		case "removed":
+			columns := tableColumns(t, func(c ColumnDiff) *DBColumn { return c.Old })
+			up = append(up, fmt.Sprintf("DROP TABLE %s;", quoteTable(t.Table)))
+			down = append(down, createTableSQL(t.Table, columns)...)
+		default:
+			table := quoteTable(t.Table)
+			for _, c := range t.Columns {
+				switch c.Change {
+				case "added":
+					up = append(up, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, columnSQL(c.New)))
+					down = append(down, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, quoteIdent(c.Column)))
+				case "removed":
+					up = append(up, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, quoteIdent(c.Column)))
+					down = append(down, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, columnSQL(c.Old)))
+				default:
+					up = append(up, alterColumnSQL(t.Table, c.Old, c.New, c.Fields)...)
+					down = append(down, alterColumnSQL(t.Table, c.New, c.Old, c.Fields)...)
+				}
+			}
+		}
+	}
+	// down undoes up from the last change
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+	return up, down
+}
+
+// tableColumns returns the columns of an added or removed table in their
+// original order.
+func tableColumns(t TableDiff, column func(ColumnDiff) *DBColumn) []DBColumn {
+	columns := make([]DBColumn, len(t.Columns))
+	for i, c := range t.Columns {
+		columns[i] = *column(c)
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+	})
+	return columns
+}
+
+// CreateSQL returns Postgres DDL creating s: enums, tables in name order and
+// their foreign keys and comments.
+func (s *Schema) CreateSQL() string {
+	var stmts []string
+	enums := make([]string, 0, len(s.Enums))
+	for name := range s.Enums {
+		enums = append(enums, name)
+	}
+	sort.Strings(enums)
+	for _, name := range enums {
+		labels := make([]string, len(s.Enums[name]))
+		for i, label := range s.Enums[name] {
+			labels[i] = quoteLiteral(label)
+		}
+		stmts = append(stmts, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);", quoteIdent(name), strings.Join(labels, ", ")))
+	}
+
+	keys := make([]string, 0, len(s.Tables))
+	for key := range s.Tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		columns := append([]DBColumn(nil), s.Tables[key]...)
+		sort.SliceStable(columns, func(i, j int) bool {
+			return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+		})
+		stmts = append(stmts, createTableSQL(key, columns)...)
+		if comment, ok := s.Comments[key]; ok {
+			stmts = append(stmts, fmt.Sprintf("COMMENT ON TABLE %s IS %s;", quoteTable(key), quoteLiteral(comment)))
+		}
+	}
+
+	// a statement per constraint, rows of one constraint are adjacent
+	var fks []string
+	for i := 0; i < len(s.ForeignKeys); {
+		fk := s.ForeignKeys[i]
+		var columns, refColumns []string
+		for ; i < len(s.ForeignKeys) && s.ForeignKeys[i].ConstraintName == fk.ConstraintName && s.ForeignKeys[i].TableName == fk.TableName; i++ {
+			columns = append(columns, quoteIdent(s.ForeignKeys[i].ColumnName))
+			refColumns = append(refColumns, quoteIdent(s.ForeignKeys[i].RefColumnName))
+		}
+		fks = append(fks, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s);",
+			quoteTable(fk.Schema+"."+fk.TableName), quoteIdent(fk.ConstraintName), strings.Join(columns, ", "),
+			quoteTable(fk.RefSchema+"."+fk.RefTableName), strings.Join(refColumns, ", ")))
+	}
+	stmts = append(stmts, fks...)
+	return strings.Join(stmts, "\n\n") + "\n"
+}
@@ -0,0 +1,163 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	_ "github.com/microsoft/go-mssqldb"
+
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// The schemas are passed joined by commas and split by STRING_SPLIT, which
+// needs SQL Server 2016.
+const (
+	mssqlTablesQuery = `
+SELECT
+	c.TABLE_SCHEMA, c.TABLE_NAME, c.COLUMN_NAME, c.ORDINAL_POSITION, c.COLUMN_DEFAULT,
+	CAST(CASE WHEN c.IS_NULLABLE = 'YES' THEN 1 ELSE 0 END AS bit), c.DATA_TYPE, c.DATA_TYPE,
+	-- (max) types report -1
+	CASE WHEN c.CHARACTER_MAXIMUM_LENGTH > 0 THEN c.CHARACTER_MAXIMUM_LENGTH END,
+	CASE WHEN c.CHARACTER_OCTET_LENGTH > 0 THEN c.CHARACTER_OCTET_LENGTH END,
+	c.NUMERIC_PRECISION, c.NUMERIC_SCALE,
+	CAST(ep.value AS nvarchar(max)),
+	CAST(CASE WHEN EXISTS (
+		SELECT 1 FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS tc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS k
+			ON k.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND k.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		WHERE tc.CONSTRAINT_TYPE = 'PRIMARY KEY' AND tc.TABLE_SCHEMA = c.TABLE_SCHEMA AND tc.TABLE_NAME = c.TABLE_NAME
+			AND k.COLUMN_NAME = c.COLUMN_NAME
+	) THEN 1 ELSE 0 END AS bit)
+FROM
+	INFORMATION_SCHEMA.COLUMNS AS c
+JOIN
+	INFORMATION_SCHEMA.TABLES AS t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+LEFT JOIN
+	sys.extended_properties AS ep
+	ON ep.class = 1 AND ep.name = 'MS_Description'
+		AND ep.major_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+		AND ep.minor_id = COLUMNPROPERTY(ep.major_id, c.COLUMN_NAME, 'ColumnId')
+WHERE
+	t.TABLE_TYPE = 'BASE TABLE' AND t.TABLE_SCHEMA IN (SELECT value FROM STRING_SPLIT(@p1, ','))
+ORDER BY
+	c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION;
+`
+
+	mssqlForeignKeysQuery = `
+SELECT
+	fk.name, SCHEMA_NAME(t.schema_id), t.name, c.name, SCHEMA_NAME(rt.schema_id), rt.name, rc.name
+FROM
+	sys.foreign_keys AS fk
+JOIN
+	sys.foreign_key_columns AS fkc ON fkc.constraint_object_id = fk.object_id
+JOIN
+	sys.tables AS t ON t.object_id = fkc.parent_object_id
+JOIN
+	sys.columns AS c ON c.object_id = fkc.parent_object_id AND c.column_id = fkc.parent_column_id
+JOIN
+	sys.tables AS rt ON rt.object_id = fkc.referenced_object_id
+JOIN
+	sys.columns AS rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE
+	SCHEMA_NAME(t.schema_id) IN (SELECT value FROM STRING_SPLIT(@p1, ','))
+ORDER BY
+	SCHEMA_NAME(t.schema_id), t.name, fk.name, fkc.constraint_column_id;
+`
+
+	mssqlTableCommentsQuery = `
+SELECT
+	SCHEMA_NAME(t.schema_id) + '.' + t.name, CAST(ep.value AS nvarchar(max))
+FROM
+	sys.tables AS t
+JOIN
+	sys.extended_properties AS ep ON ep.class = 1 AND ep.major_id = t.object_id AND ep.minor_id = 0 AND ep.name = 'MS_Description'
+WHERE
+	SCHEMA_NAME(t.schema_id) IN (SELECT value FROM STRING_SPLIT(@p1, ','));
+`
+
+	mssqlSchemasQuery = `
+SELECT DISTINCT
+	TABLE_SCHEMA
+FROM
+	INFORMATION_SCHEMA.TABLES
+WHERE
+	TABLE_TYPE = 'BASE TABLE' AND TABLE_SCHEMA NOT IN ('sys', 'INFORMATION_SCHEMA')
+ORDER BY
+	TABLE_SCHEMA;
+`
+
+	mssqlSchemaHashQuery = `
+SELECT
+	CONCAT('column ', TABLE_SCHEMA, ' ', TABLE_NAME, ' ', COLUMN_NAME, ' ', ORDINAL_POSITION, ' ', DATA_TYPE, ' ',
+		IS_NULLABLE, ' ', COLUMN_DEFAULT, ' ', CHARACTER_MAXIMUM_LENGTH)
+FROM
+	INFORMATION_SCHEMA.COLUMNS
+WHERE
+	TABLE_SCHEMA IN (SELECT value FROM STRING_SPLIT(@p1, ','))
+UNION ALL
+SELECT
+	CONCAT('constraint ', TABLE_SCHEMA, ' ', TABLE_NAME, ' ', CONSTRAINT_NAME, ' ', COLUMN_NAME, ' ', ORDINAL_POSITION)
+FROM
+	INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+WHERE
+	TABLE_SCHEMA IN (SELECT value FROM STRING_SPLIT(@p1, ','))
+UNION ALL
+SELECT
+	CONCAT('comment ', OBJECT_SCHEMA_NAME(major_id), ' ', OBJECT_NAME(major_id), ' ', minor_id, ' ', CAST(value AS nvarchar(max)))
+FROM
+	sys.extended_properties
+WHERE
+	class = 1 AND name = 'MS_Description'
+	AND OBJECT_SCHEMA_NAME(major_id) IN (SELECT value FROM STRING_SPLIT(@p1, ','))
+ORDER BY
+	1;
+`
+)
+
+// MSSQL reads the information schema and catalog views of SQL Server.
+// Comments are MS_Description extended properties, there are no enums.
+// Triggers and security policies aren't read.
+var MSSQL Dialect = mssql{}
+
+type mssql struct{}
+
+func (mssql) Name() string {
+	return "mssql"
+}
+
+func (mssql) Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error) {
+	return queryColumns(ctx, db, mssqlTablesQuery, strings.Join(schemas, ","))
+}
+
+func (mssql) ForeignKeys(ctx context.Context, db *sql.DB, schemas []string) ([]DBForeignKey, error) {
+	return queryForeignKeys(ctx, db, mssqlForeignKeysQuery, strings.Join(schemas, ","))
+}
+
+func (mssql) TableComments(ctx context.Context, db *sql.DB, schemas []string) (map[string]string, error) {
+	return queryComments(ctx, db, mssqlTableCommentsQuery, strings.Join(schemas, ","))
+}
+
+func (mssql) Triggers(ctx context.Context, db *sql.DB, schemas []string) (DBTriggers, error) {
+	return DBTriggers{}, nil
+}
+
+func (mssql) RowSecurity(ctx context.Context, db *sql.DB, schemas []string) (DBRowSecurity, error) {
+	return DBRowSecurity{}, nil
+}
+
+func (mssql) Schemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "schemas", mssqlSchemasQuery)
+}
+
+func (mssql) Enums(ctx context.Context, db *sql.DB, schemas []string) (DBEnums, error) {
+	return DBEnums{}, nil
+}
+
+func (mssql) SchemaHash(ctx context.Context, db *sql.DB, schemas []string) (string, error) {
+	return queryHash(ctx, db, mssqlSchemaHashQuery, strings.Join(schemas, ","))
+}
+
+func (mssql) Types() *typemap.TypesMapping {
+	return typemap.NewMSSQLTypesMapping()
+}
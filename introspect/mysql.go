@@ -0,0 +1,187 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// The schemas are passed joined by commas, the MySQL driver has no arrays.
+const (
+	mysqlTablesQuery = `
+SELECT
+	c.table_schema, c.table_name, c.column_name, c.ordinal_position, c.column_default, c.is_nullable = 'YES', c.data_type,
+	CASE
+		WHEN c.column_type = 'tinyint(1)' THEN 'bool'
+		WHEN c.data_type = 'enum' THEN concat(c.table_name, '_', c.column_name)
+		WHEN c.column_type LIKE '% unsigned%' THEN concat(c.data_type, ' unsigned')
+		ELSE c.data_type
+	END,
+	c.character_maximum_length, c.character_octet_length, c.numeric_precision, c.numeric_scale,
+	nullif(c.column_comment, ''), c.column_key = 'PRI'
+FROM
+	information_schema.columns AS c
+JOIN
+	information_schema.tables AS t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE
+	find_in_set(t.table_schema, ?) AND t.table_type = 'BASE TABLE'
+ORDER BY
+	c.table_schema, c.table_name, c.ordinal_position;
+`
+
+	mysqlForeignKeysQuery = `
+SELECT
+	constraint_name, table_schema, table_name, column_name,
+	referenced_table_schema, referenced_table_name, referenced_column_name
+FROM
+	information_schema.key_column_usage
+WHERE
+	referenced_table_name IS NOT NULL AND find_in_set(table_schema, ?)
+ORDER BY
+	table_schema, table_name, constraint_name, ordinal_position;
+`
+
+	mysqlTableCommentsQuery = `
+SELECT
+	concat(table_schema, '.', table_name), table_comment
+FROM
+	information_schema.tables
+WHERE
+	find_in_set(table_schema, ?) AND table_type = 'BASE TABLE' AND table_comment <> '';
+`
+
+	mysqlSchemasQuery = `
+SELECT DISTINCT
+	table_schema
+FROM
+	information_schema.tables
+WHERE
+	table_type = 'BASE TABLE' AND table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
+ORDER BY
+	table_schema;
+`
+
+	// enums are column types in MySQL, they are named after their column
+	mysqlEnumsQuery = `
+SELECT
+	concat(table_name, '_', column_name), column_type
+FROM
+	information_schema.columns
+WHERE
+	find_in_set(table_schema, ?) AND data_type = 'enum';
+`
+
+	mysqlSchemaHashQuery = `
+SELECT
+	concat_ws(' ', 'column', table_schema, table_name, column_name, ordinal_position, column_type,
+		is_nullable, column_default, column_key, column_comment)
+FROM
+	information_schema.columns
+WHERE
+	find_in_set(table_schema, ?)
+UNION ALL
+SELECT
+	concat_ws(' ', 'constraint', table_schema, table_name, constraint_name, column_name,
+		referenced_table_schema, referenced_table_name, referenced_column_name)
+FROM
+	information_schema.key_column_usage
+WHERE
+	find_in_set(table_schema, ?)
+UNION ALL
+SELECT
+	concat_ws(' ', 'comment', table_schema, table_name, table_comment)
+FROM
+	information_schema.tables
+WHERE
+	find_in_set(table_schema, ?)
+ORDER BY
+	1;
+`
+)
+
+// MySQL reads the information schema of MySQL, where schemas are databases.
+// Triggers and row level security are only read from Postgres.
+var MySQL Dialect = mysql{}
+
+type mysql struct{}
+
+func (mysql) Name() string {
+	return "mysql"
+}
+
+func (mysql) Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error) {
+	return queryColumns(ctx, db, mysqlTablesQuery, strings.Join(schemas, ","))
+}
+
+func (mysql) ForeignKeys(ctx context.Context, db *sql.DB, schemas []string) ([]DBForeignKey, error) {
+	return queryForeignKeys(ctx, db, mysqlForeignKeysQuery, strings.Join(schemas, ","))
+}
+
+func (mysql) TableComments(ctx context.Context, db *sql.DB, schemas []string) (map[string]string, error) {
+	return queryComments(ctx, db, mysqlTableCommentsQuery, strings.Join(schemas, ","))
+}
+
+func (mysql) Triggers(ctx context.Context, db *sql.DB, schemas []string) (DBTriggers, error) {
+	return DBTriggers{}, nil
+}
+
+func (mysql) RowSecurity(ctx context.Context, db *sql.DB, schemas []string) (DBRowSecurity, error) {
+	return DBRowSecurity{}, nil
+}
+
+func (mysql) Schemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "schemas", mysqlSchemasQuery)
+}
+
+func (mysql) Enums(ctx context.Context, db *sql.DB, schemas []string) (DBEnums, error) {
+	pairs, err := queryPairs(ctx, db, "enums", mysqlEnumsQuery, strings.Join(schemas, ","))
+	if err != nil {
+		return nil, err
+	}
+	enums := make(DBEnums, len(pairs))
+	for _, p := range pairs {
+		enums[p[0]] = parseMySQLEnum(p[1])
+	}
+	return enums, nil
+}
+
+func (mysql) SchemaHash(ctx context.Context, db *sql.DB, schemas []string) (string, error) {
+	list := strings.Join(schemas, ",")
+	return queryHash(ctx, db, mysqlSchemaHashQuery, list, list, list)
+}
+
+func (mysql) Types() *typemap.TypesMapping {
+	return typemap.NewMySQLTypesMapping()
+}
+
+// parseMySQLEnum returns the labels of a column type like enum('a','b'),
+// where quotes within labels are doubled.
+func parseMySQLEnum(columnType string) []string {
+	list := strings.TrimSuffix(strings.TrimPrefix(columnType, "enum("), ")")
+	var (
+		labels []string
+		label  strings.Builder
+		quoted bool
+	)
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(list) && list[i+1] == '\'':
+			label.WriteByte(c)
+			i++
+		case c == '\'':
+			quoted = !quoted
+			if !quoted {
+				labels = append(labels, label.String())
+				label.Reset()
+			}
+		case quoted:
+			label.WriteByte(c)
+		}
+	}
+	return labels
+}
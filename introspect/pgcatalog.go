@@ -0,0 +1,80 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// The information schema only shows columns the current user has privileges
+// on, lists partitions as tables of their own and is slow on large databases.
+// This reads the same from the catalogs, with types and typmods resolved like
+// the information schema does, domains to their base type.
+const postgresCatalogTablesQuery = `
+SELECT
+	n.nspname, c.relname, a.attname, a.attnum,
+	CASE
+		WHEN a.attidentity <> '' THEN
+			'generated ' || CASE a.attidentity WHEN 'a' THEN 'always' ELSE 'by default' END || ' as identity (sequence name ' ||
+			pg_get_serial_sequence(format('%I.%I', n.nspname, c.relname), a.attname) || ')'
+		WHEN a.attgenerated = '' THEN pg_get_expr(d.adbin, d.adrelid)
+		ELSE
+			'generated always as (' || pg_get_expr(d.adbin, d.adrelid) || ') ' ||
+			CASE a.attgenerated WHEN 'v' THEN 'virtual' ELSE 'stored' END
+	END,
+	NOT (a.attnotnull OR t.typtype = 'd' AND t.typnotnull),
+	CASE
+		WHEN bt.typcategory = 'A' THEN 'ARRAY'
+		WHEN bt.typtype = 'b' AND bn.nspname = 'pg_catalog' THEN format_type(bt.oid, NULL)
+		ELSE 'USER-DEFINED'
+	END,
+	bt.typname,
+	coalesce(
+		information_schema._pg_char_max_length(bt.oid, tm.typmod),
+		-- pgvector keeps the dimensions in the type modifier
+		CASE WHEN bt.typname = 'vector' THEN nullif(tm.typmod, -1) END
+	),
+	information_schema._pg_char_octet_length(bt.oid, tm.typmod),
+	information_schema._pg_numeric_precision(bt.oid, tm.typmod),
+	information_schema._pg_numeric_scale(bt.oid, tm.typmod),
+	col_description(c.oid, a.attnum),
+	EXISTS (
+		SELECT 1 FROM pg_index AS i
+		WHERE i.indrelid = c.oid AND i.indisprimary AND a.attnum = ANY(i.indkey)
+	)
+FROM
+	pg_attribute AS a
+JOIN
+	pg_class AS c ON c.oid = a.attrelid
+JOIN
+	pg_namespace AS n ON n.oid = c.relnamespace
+JOIN
+	pg_type AS t ON t.oid = a.atttypid
+CROSS JOIN LATERAL (
+	SELECT information_schema._pg_truetypid(a, t) AS typid, information_schema._pg_truetypmod(a, t) AS typmod
+) AS tm
+JOIN
+	pg_type AS bt ON bt.oid = tm.typid
+JOIN
+	pg_namespace AS bn ON bn.oid = bt.typnamespace
+LEFT JOIN
+	pg_attrdef AS d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE
+	n.nspname = ANY($1) AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+	AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY
+	n.nspname, c.relname, a.attnum;
+`
+
+// PostgresCatalog is Postgres with tables read from pg_class, pg_attribute
+// and pg_type instead of the information schema.
+var PostgresCatalog Dialect = postgresCatalog{}
+
+type postgresCatalog struct {
+	postgres
+}
+
+func (postgresCatalog) Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error) {
+	return queryColumns(ctx, db, postgresCatalogTablesQuery, pq.Array(schemas))
+}
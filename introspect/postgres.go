@@ -0,0 +1,354 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+const (
+	postgresTablesQuery = `
+SELECT 
+	c.table_schema, c.table_name, c.column_name, c.ordinal_position,
+	-- identity and generated columns get their clause as the default, as in
+	-- parsed DDL
+	coalesce(c.column_default, CASE
+		WHEN c.is_identity = 'YES' THEN
+			'generated ' || lower(c.identity_generation) || ' as identity (sequence name ' ||
+			pg_get_serial_sequence(format('%I.%I', c.table_schema, c.table_name), c.column_name) || ')'
+		WHEN c.is_generated = 'ALWAYS' THEN
+			'generated always as (' || c.generation_expression || ') ' || (
+				SELECT CASE a.attgenerated WHEN 'v' THEN 'virtual' ELSE 'stored' END FROM pg_attribute AS a
+				WHERE a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+					AND a.attnum = c.ordinal_position
+			)
+	END),
+	bool(c.is_nullable), c.data_type, c.udt_name,
+	coalesce(c.character_maximum_length, nullif((
+		-- pgvector keeps the dimensions in the type modifier
+		SELECT a.atttypmod FROM pg_attribute AS a
+		WHERE a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+			AND a.attnum = c.ordinal_position AND c.udt_name = 'vector'
+	), -1)),
+	c.character_octet_length, c.numeric_precision, c.numeric_scale,
+	col_description(format('%I.%I', c.table_schema, c.table_name)::regclass, c.ordinal_position),
+	EXISTS (
+		SELECT 1 FROM pg_index AS i
+		WHERE i.indrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+			AND i.indisprimary AND c.ordinal_position = ANY(i.indkey)
+	)
+FROM 
+	information_schema.columns AS c 
+JOIN
+	information_schema.tables as t
+ON
+	t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE 
+	t.table_schema = ANY($1) AND t.table_type = 'BASE TABLE'
+ORDER BY 
+	c.table_schema, c.table_name;
+`
+
+	postgresForeignKeysQuery = `
+SELECT
+	con.conname, ns.nspname, cl.relname, att.attname, rns.nspname, rcl.relname, ratt.attname
+FROM
+	pg_constraint AS con
+JOIN
+	pg_class AS cl ON cl.oid = con.conrelid
+JOIN
+	pg_namespace AS ns ON ns.oid = cl.relnamespace
+JOIN
+	pg_class AS rcl ON rcl.oid = con.confrelid
+JOIN
+	pg_namespace AS rns ON rns.oid = rcl.relnamespace
+JOIN LATERAL
+	unnest(con.conkey, con.confkey) AS k(attnum, refattnum) ON true
+JOIN
+	pg_attribute AS att ON att.attrelid = con.conrelid AND att.attnum = k.attnum
+JOIN
+	pg_attribute AS ratt ON ratt.attrelid = con.confrelid AND ratt.attnum = k.refattnum
+WHERE
+	con.contype = 'f' AND ns.nspname = ANY($1)
+ORDER BY
+	ns.nspname, cl.relname, con.conname;
+`
+
+	postgresTableCommentsQuery = `
+SELECT
+	n.nspname || '.' || c.relname, d.description
+FROM
+	pg_class AS c
+JOIN
+	pg_namespace AS n ON n.oid = c.relnamespace
+JOIN
+	pg_description AS d ON d.objoid = c.oid AND d.objsubid = 0
+WHERE
+	n.nspname = ANY($1) AND c.relkind IN ('r', 'p');
+`
+
+	postgresTriggersQuery = `
+SELECT
+	n.nspname, c.relname, t.tgname,
+	CASE
+		WHEN t.tgtype::int & 2 <> 0 THEN 'BEFORE'
+		WHEN t.tgtype::int & 64 <> 0 THEN 'INSTEAD OF'
+		ELSE 'AFTER'
+	END,
+	array_remove(ARRAY[
+		CASE WHEN t.tgtype::int & 4 <> 0 THEN 'INSERT' END,
+		CASE WHEN t.tgtype::int & 16 <> 0 THEN 'UPDATE' END,
+		CASE WHEN t.tgtype::int & 8 <> 0 THEN 'DELETE' END,
+		CASE WHEN t.tgtype::int & 32 <> 0 THEN 'TRUNCATE' END
+	], NULL),
+	CASE WHEN t.tgtype::int & 1 <> 0 THEN 'ROW' ELSE 'STATEMENT' END,
+	t.tgfoid::regproc::text
+FROM
+	pg_trigger AS t
+JOIN
+	pg_class AS c ON c.oid = t.tgrelid
+JOIN
+	pg_namespace AS n ON n.oid = c.relnamespace
+WHERE
+	n.nspname = ANY($1) AND NOT t.tgisinternal
+ORDER BY
+	n.nspname, c.relname, t.tgname;
+`
+
+	postgresRowSecurityQuery = `
+SELECT
+	n.nspname, c.relname, c.relforcerowsecurity,
+	p.policyname, p.permissive = 'RESTRICTIVE', p.cmd, p.roles, p.qual, p.with_check
+FROM
+	pg_class AS c
+JOIN
+	pg_namespace AS n ON n.oid = c.relnamespace
+LEFT JOIN
+	pg_policies AS p ON p.schemaname = n.nspname AND p.tablename = c.relname
+WHERE
+	n.nspname = ANY($1) AND c.relkind IN ('r', 'p') AND c.relrowsecurity
+ORDER BY
+	n.nspname, c.relname, p.policyname;
+`
+
+	postgresSchemasQuery = `
+SELECT DISTINCT
+	table_schema
+FROM
+	information_schema.tables
+WHERE
+	table_type = 'BASE TABLE' AND table_schema NOT IN ('information_schema', 'pg_catalog')
+	AND table_schema NOT LIKE 'pg\_%'
+ORDER BY
+	table_schema;
+`
+
+	postgresEnumsQuery = `
+SELECT
+	t.typname, e.enumlabel
+FROM
+	pg_type AS t
+JOIN
+	pg_enum AS e ON e.enumtypid = t.oid
+JOIN
+	pg_namespace AS n ON n.oid = t.typnamespace
+WHERE
+	n.nspname = ANY($1)
+ORDER BY
+	t.typname, e.enumsortorder;
+`
+
+	postgresSchemaHashQuery = `
+SELECT md5(coalesce(string_agg(item, E'\n' ORDER BY item), ''))
+FROM (
+	SELECT
+		format('column %s.%s.%s %s %s %s %s %s %s %s', table_schema, table_name, column_name, ordinal_position,
+			udt_name, is_nullable, column_default, character_maximum_length, numeric_precision, numeric_scale) AS item
+	FROM
+		information_schema.columns
+	WHERE
+		table_schema = ANY($1)
+	UNION ALL
+	SELECT
+		format('constraint %s %s %s', con.conrelid::regclass, con.conname, pg_get_constraintdef(con.oid))
+	FROM
+		pg_constraint AS con
+	JOIN
+		pg_namespace AS n ON n.oid = con.connamespace
+	WHERE
+		n.nspname = ANY($1)
+	UNION ALL
+	SELECT
+		format('enum %s %s %s', t.typname, e.enumsortorder, e.enumlabel)
+	FROM
+		pg_enum AS e
+	JOIN
+		pg_type AS t ON t.oid = e.enumtypid
+	JOIN
+		pg_namespace AS n ON n.oid = t.typnamespace
+	WHERE
+		n.nspname = ANY($1)
+	UNION ALL
+	SELECT
+		format('comment %s %s %s', c.oid::regclass, d.objsubid, d.description)
+	FROM
+		pg_description AS d
+	JOIN
+		pg_class AS c ON c.oid = d.objoid
+	JOIN
+		pg_namespace AS n ON n.oid = c.relnamespace
+	WHERE
+		n.nspname = ANY($1)
+	UNION ALL
+	SELECT
+		format('typmod %s %s %s', a.attrelid::regclass, a.attname, a.atttypmod)
+	FROM
+		pg_attribute AS a
+	JOIN
+		pg_type AS t ON t.oid = a.atttypid
+	JOIN
+		pg_class AS c ON c.oid = a.attrelid
+	JOIN
+		pg_namespace AS n ON n.oid = c.relnamespace
+	WHERE
+		n.nspname = ANY($1) AND t.typname = 'vector'
+	UNION ALL
+	SELECT
+		format('trigger %s %s', t.tgrelid::regclass, pg_get_triggerdef(t.oid))
+	FROM
+		pg_trigger AS t
+	JOIN
+		pg_class AS c ON c.oid = t.tgrelid
+	JOIN
+		pg_namespace AS n ON n.oid = c.relnamespace
+	WHERE
+		n.nspname = ANY($1) AND NOT t.tgisinternal
+	UNION ALL
+	SELECT
+		format('row security %s %s %s', c.oid::regclass, c.relrowsecurity, c.relforcerowsecurity)
+	FROM
+		pg_class AS c
+	JOIN
+		pg_namespace AS n ON n.oid = c.relnamespace
+	WHERE
+		n.nspname = ANY($1) AND c.relrowsecurity
+	UNION ALL
+	SELECT
+		format('policy %s.%s %s %s %s %s %s %s', p.schemaname, p.tablename, p.policyname, p.permissive, p.cmd, p.roles, p.qual, p.with_check)
+	FROM
+		pg_policies AS p
+	WHERE
+		p.schemaname = ANY($1)
+) AS items;
+`
+)
+
+// Postgres reads the information schema and catalogs of Postgres.
+var Postgres Dialect = postgres{}
+
+type postgres struct{}
+
+func (postgres) Name() string {
+	return "postgres"
+}
+
+func (postgres) Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error) {
+	return queryColumns(ctx, db, postgresTablesQuery, pq.Array(schemas))
+}
+
+func (postgres) ForeignKeys(ctx context.Context, db *sql.DB, schemas []string) ([]DBForeignKey, error) {
+	return queryForeignKeys(ctx, db, postgresForeignKeysQuery, pq.Array(schemas))
+}
+
+func (postgres) TableComments(ctx context.Context, db *sql.DB, schemas []string) (map[string]string, error) {
+	return queryComments(ctx, db, postgresTableCommentsQuery, pq.Array(schemas))
+}
+
+func (postgres) Triggers(ctx context.Context, db *sql.DB, schemas []string) (DBTriggers, error) {
+	rows, err := db.QueryContext(ctx, postgresTriggersQuery, pq.Array(schemas))
+	if err != nil {
+		return nil, &Error{Op: "query", Object: "triggers", Err: err}
+	}
+	defer rows.Close()
+
+	triggers := make(DBTriggers)
+	for rows.Next() {
+		var schema, table string
+		var t DBTrigger
+		if err := rows.Scan(&schema, &table, &t.Name, &t.Timing, pq.Array(&t.Events), &t.Level, &t.Function); err != nil {
+			return nil, &Error{Op: "scan", Object: "triggers", Err: err}
+		}
+		triggers[schema+"."+table] = append(triggers[schema+"."+table], t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &Error{Op: "scan", Object: "triggers", Err: err}
+	}
+	return triggers, nil
+}
+
+func (postgres) RowSecurity(ctx context.Context, db *sql.DB, schemas []string) (DBRowSecurity, error) {
+	rows, err := db.QueryContext(ctx, postgresRowSecurityQuery, pq.Array(schemas))
+	if err != nil {
+		return nil, &Error{Op: "query", Object: "row security", Err: err}
+	}
+	defer rows.Close()
+
+	security := make(DBRowSecurity)
+	for rows.Next() {
+		var (
+			schema, table string
+			forced        bool
+			name, command sql.NullString
+			restrictive   sql.NullBool
+			roles         []string
+			using, check  sql.NullString
+		)
+		if err := rows.Scan(&schema, &table, &forced, &name, &restrictive, &command, pq.Array(&roles), &using, &check); err != nil {
+			return nil, &Error{Op: "scan", Object: "row security", Err: err}
+		}
+		key := schema + "." + table
+		rls := security[key]
+		rls.Forced = forced
+		if name.Valid {
+			rls.Policies = append(rls.Policies, DBPolicy{
+				Name:        name.String,
+				Restrictive: restrictive.Bool,
+				Command:     command.String,
+				Roles:       roles,
+				Using:       using.String,
+				Check:       check.String,
+			})
+		}
+		security[key] = rls
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &Error{Op: "scan", Object: "row security", Err: err}
+	}
+	return security, nil
+}
+
+func (postgres) Schemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "schemas", postgresSchemasQuery)
+}
+
+func (postgres) Enums(ctx context.Context, db *sql.DB, schemas []string) (DBEnums, error) {
+	return queryEnums(ctx, db, postgresEnumsQuery, pq.Array(schemas))
+}
+
+func (postgres) SchemaHash(ctx context.Context, db *sql.DB, schemas []string) (string, error) {
+	var hash string
+	if err := db.QueryRowContext(ctx, postgresSchemaHashQuery, pq.Array(schemas)).Scan(&hash); err != nil {
+		return "", &Error{Op: "query", Object: "schema hash", Err: err}
+	}
+	return hash, nil
+}
+
+func (postgres) Types() *typemap.TypesMapping {
+	return typemap.NewTypesMapping()
+}
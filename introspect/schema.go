@@ -0,0 +1,128 @@
+package introspect
+
+import (
+	"context"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// Source is what generation reads a schema from, an Introspector or a
+// Schema read without a database.
+type Source interface {
+	Tables(ctx context.Context, schemas []string) (DBTables, error)
+	ForeignKeys(ctx context.Context, schemas []string) ([]DBForeignKey, error)
+	TableComments(ctx context.Context, schemas []string) (map[string]string, error)
+	Triggers(ctx context.Context, schemas []string) (DBTriggers, error)
+	RowSecurity(ctx context.Context, schemas []string) (DBRowSecurity, error)
+	Enums(ctx context.Context, schemas []string) (DBEnums, error)
+	Types() *typemap.TypesMapping
+	Close() error
+}
+
+// Schema is a schema held in memory, e.g. parsed from DDL or loaded from a
+// snapshot.
+type Schema struct {
+	// Version is the snapshot format version, see SnapshotVersion.
+	Version int `json:"version" yaml:"version"`
+	// Dialect names the entry of Dialects whose types the schema uses,
+	// postgres if empty.
+	Dialect string `json:"dialect" yaml:"dialect"`
+	// Schemas are the schemas read, if known.
+	Schemas     []string       `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	Tables      DBTables       `json:"tables" yaml:"tables"`
+	ForeignKeys []DBForeignKey `json:"foreign_keys" yaml:"foreign_keys"`
+	// Comments are the table comments keyed by "schema.table".
+	Comments map[string]string `json:"comments" yaml:"comments"`
+	Enums    DBEnums           `json:"enums" yaml:"enums"`
+	// Triggers are keyed by "schema.table".
+	Triggers DBTriggers `json:"triggers,omitempty" yaml:"triggers,omitempty"`
+	// RowSecurity is keyed by "schema.table".
+	RowSecurity DBRowSecurity `json:"row_security,omitempty" yaml:"row_security,omitempty"`
+}
+
+// Source returns a Source reading s, restricted to the schemas asked for
+// like an Introspector's.
+func (s *Schema) Source() Source {
+	return schemaSource{s}
+}
+
+type schemaSource struct {
+	s *Schema
+}
+
+func inSchemas(schema string, schemas []string) bool {
+	for _, s := range schemas {
+		if s == schema {
+			return true
+		}
+	}
+	return false
+}
+
+func (src schemaSource) Tables(ctx context.Context, schemas []string) (DBTables, error) {
+	tables := make(DBTables)
+	for key, columns := range src.s.Tables {
+		if inSchemas(columns[0].TableSchema, schemas) {
+			tables[key] = columns
+			report.Steps.Add(1)
+		}
+	}
+	return tables, nil
+}
+
+func (src schemaSource) ForeignKeys(ctx context.Context, schemas []string) ([]DBForeignKey, error) {
+	var fks []DBForeignKey
+	for _, fk := range src.s.ForeignKeys {
+		if inSchemas(fk.Schema, schemas) {
+			fks = append(fks, fk)
+		}
+	}
+	return fks, nil
+}
+
+func (src schemaSource) TableComments(ctx context.Context, schemas []string) (map[string]string, error) {
+	comments := make(map[string]string)
+	for key, comment := range src.s.Comments {
+		if columns, ok := src.s.Tables[key]; ok && inSchemas(columns[0].TableSchema, schemas) {
+			comments[key] = comment
+		}
+	}
+	return comments, nil
+}
+
+func (src schemaSource) Triggers(ctx context.Context, schemas []string) (DBTriggers, error) {
+	triggers := make(DBTriggers)
+	for key, list := range src.s.Triggers {
+		if columns, ok := src.s.Tables[key]; ok && inSchemas(columns[0].TableSchema, schemas) {
+			triggers[key] = list
+		}
+	}
+	return triggers, nil
+}
+
+func (src schemaSource) RowSecurity(ctx context.Context, schemas []string) (DBRowSecurity, error) {
+	security := make(DBRowSecurity)
+	for key, rls := range src.s.RowSecurity {
+		if columns, ok := src.s.Tables[key]; ok && inSchemas(columns[0].TableSchema, schemas) {
+			security[key] = rls
+		}
+	}
+	return security, nil
+}
+
+// Enums returns all enums, a Schema doesn't record the schema of types.
+func (src schemaSource) Enums(ctx context.Context, schemas []string) (DBEnums, error) {
+	return src.s.Enums, nil
+}
+
+func (src schemaSource) Types() *typemap.TypesMapping {
+	if d, ok := Dialects[src.s.Dialect]; ok {
+		return d.Types()
+	}
+	return Postgres.Types()
+}
+
+func (src schemaSource) Close() error {
+	return nil
+}
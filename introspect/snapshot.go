@@ -0,0 +1,118 @@
+package introspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// WriteSnapshot.
+const SnapshotVersion = 1
+
+// Snapshot reads everything generation needs from schemas of src.
+func Snapshot(ctx context.Context, src Source, dialect string, schemas []string) (*Schema, error) {
+	s := &Schema{
+		Version: SnapshotVersion,
+		Dialect: dialect,
+		Schemas: schemas,
+	}
+	var err error
+	if s.Tables, err = src.Tables(ctx, schemas); err != nil {
+		return nil, err
+	}
+	if s.ForeignKeys, err = src.ForeignKeys(ctx, schemas); err != nil {
+		return nil, err
+	}
+	if s.Comments, err = src.TableComments(ctx, schemas); err != nil {
+		return nil, err
+	}
+	if s.Triggers, err = src.Triggers(ctx, schemas); err != nil {
+		return nil, err
+	}
+	if s.RowSecurity, err = src.RowSecurity(ctx, schemas); err != nil {
+		return nil, err
+	}
+	if s.Enums, err = src.Enums(ctx, schemas); err != nil {
+		return nil, err
+	}
+	// rows come in whatever order the database returns them, a snapshot
+	// should only change with the schema
+	for _, columns := range s.Tables {
+		sort.Slice(columns, func(i, j int) bool {
+			return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+		})
+	}
+	sort.SliceStable(s.ForeignKeys, func(i, j int) bool {
+		a, b := s.ForeignKeys[i], s.ForeignKeys[j]
+		if a.Schema+"."+a.TableName != b.Schema+"."+b.TableName {
+			return a.Schema+"."+a.TableName < b.Schema+"."+b.TableName
+		}
+		return a.ConstraintName < b.ConstraintName
+	})
+	return s, nil
+}
+
+// WriteSnapshot writes s to w as "json" or "yaml".
+func WriteSnapshot(w io.Writer, s *Schema, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	return fmt.Errorf("unknown snapshot format %q, use json or yaml", format)
+}
+
+// LoadSnapshot reads a snapshot written by WriteSnapshot, YAML unless path
+// ends in .json.
+func LoadSnapshot(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Schema)
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(s)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d", path, s.Version)
+	}
+	if _, ok := Dialects[s.Dialect]; !ok && s.Dialect != "" {
+		return nil, fmt.Errorf("%s: unknown dialect %q", path, s.Dialect)
+	}
+	for key, columns := range s.Tables {
+		if len(columns) == 0 {
+			delete(s.Tables, key)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,138 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// The queries are run once per schema, {{schema}} is replaced by its quoted
+// name, "main" or an attached database.
+const (
+	sqliteTablesQuery = `
+SELECT
+	?, m.name, p.name, p.cid + 1, p.dflt_value, p."notnull" = 0 AND p.pk = 0, lower(p.type),
+	lower(trim(CASE WHEN instr(p.type, '(') > 0 THEN substr(p.type, 1, instr(p.type, '(') - 1) ELSE p.type END)),
+	NULL, NULL, NULL, NULL, NULL, p.pk > 0
+FROM
+	{{schema}}.sqlite_master AS m, pragma_table_info(m.name, ?) AS p
+WHERE
+	m.type = 'table' AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+ORDER BY
+	m.name, p.cid;
+`
+
+	// SQLite has no constraint names, foreign keys are numbered per table
+	sqliteForeignKeysQuery = `
+SELECT
+	'fk_' || m.name || '_' || f.id, ?, m.name, f."from", ?, f."table",
+	coalesce(f."to", (SELECT p.name FROM pragma_table_info(f."table", ?) AS p WHERE p.pk = 1))
+FROM
+	{{schema}}.sqlite_master AS m, pragma_foreign_key_list(m.name, ?) AS f
+WHERE
+	m.type = 'table'
+ORDER BY
+	m.name, f.id, f.seq;
+`
+
+	sqliteSchemasQuery = `
+SELECT
+	name
+FROM
+	pragma_database_list
+WHERE
+	name <> 'temp'
+ORDER BY
+	seq;
+`
+
+	// a part of a UNION ALL over all schemas
+	sqliteSchemaHashQuery = `
+SELECT
+	? || ' ' || m.type || ' ' || m.name || ' ' || coalesce(m.sql, '')
+FROM
+	{{schema}}.sqlite_master AS m
+`
+)
+
+// SQLite reads the schema table and table pragmas of SQLite databases, where
+// schemas are "main" and the attached databases. It has neither enums,
+// comments nor row level security, and its triggers aren't read.
+var SQLite Dialect = sqlite{}
+
+type sqlite struct{}
+
+func (sqlite) Name() string {
+	return "sqlite"
+}
+
+func (sqlite) Tables(ctx context.Context, db *sql.DB, schemas []string) (DBTables, error) {
+	tables := make(DBTables)
+	for _, schema := range schemas {
+		found, err := queryColumns(ctx, db, sqliteQuery(sqliteTablesQuery, schema), schema, schema)
+		if err != nil {
+			return nil, err
+		}
+		for key, columns := range found {
+			tables[key] = columns
+		}
+	}
+	return tables, nil
+}
+
+func (sqlite) ForeignKeys(ctx context.Context, db *sql.DB, schemas []string) ([]DBForeignKey, error) {
+	var fks []DBForeignKey
+	for _, schema := range schemas {
+		found, err := queryForeignKeys(ctx, db, sqliteQuery(sqliteForeignKeysQuery, schema), schema, schema, schema, schema)
+		if err != nil {
+			return nil, err
+		}
+		fks = append(fks, found...)
+	}
+	return fks, nil
+}
+
+func (sqlite) TableComments(ctx context.Context, db *sql.DB, schemas []string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (sqlite) Triggers(ctx context.Context, db *sql.DB, schemas []string) (DBTriggers, error) {
+	return DBTriggers{}, nil
+}
+
+func (sqlite) RowSecurity(ctx context.Context, db *sql.DB, schemas []string) (DBRowSecurity, error) {
+	return DBRowSecurity{}, nil
+}
+
+func (sqlite) Schemas(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryStrings(ctx, db, "schemas", sqliteSchemasQuery)
+}
+
+func (sqlite) Enums(ctx context.Context, db *sql.DB, schemas []string) (DBEnums, error) {
+	return DBEnums{}, nil
+}
+
+func (sqlite) SchemaHash(ctx context.Context, db *sql.DB, schemas []string) (string, error) {
+	var (
+		parts []string
+		args  []interface{}
+	)
+	for _, schema := range schemas {
+		parts = append(parts, sqliteQuery(sqliteSchemaHashQuery, schema))
+		args = append(args, schema)
+	}
+	return queryHash(ctx, db, strings.Join(parts, "UNION ALL")+"ORDER BY 1;", args...)
+}
+
+func (sqlite) Types() *typemap.TypesMapping {
+	return typemap.NewSQLiteTypesMapping()
+}
+
+// sqliteQuery puts the quoted schema name into q.
+func sqliteQuery(q, schema string) string {
+	return strings.Replace(q, "{{schema}}", `"`+strings.Replace(schema, `"`, `""`, -1)+`"`, -1)
+}
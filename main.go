@@ -1,283 +1,286 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"database/sql"
-	"errors"
-	"flag"
+	"context"
 	"fmt"
-	"go/format"
 	"log"
 	"os"
-	"sort"
+	"os/signal"
+	"path/filepath"
 	"strings"
-	"text/template"
-	"unicode"
-
-	_ "github.com/lib/pq"
-)
-
-const (
-	headerTpl = `
-package models
-
-import (
 	"time"
-)
 
-var (
-	_ = time.Time{}
+	"github.com/asyndrige/postgres-model-generator/codegen"
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+	"github.com/asyndrige/postgres-model-generator/introspect"
+	"github.com/asyndrige/postgres-model-generator/typemap"
 )
-`
-
-	modelTpl = "type {{.Name}} struct {\ntableName struct{} `sql:\"{{.TableName}}\"`\n{{range .Fields}}\t{{.Name}} {{.Type}} `{{.Tag}}`\n{{end}} }\n\n"
-)
-
-type DBTables map[string][]DBColumn
-
-type DBColumn struct {
-	ColumnName             string
-	OrdinalPosition        int
-	ColumnDefault          *string
-	IsNullable             bool
-	DataType               string
-	UDTName                string
-	CharacterMaximumLength *int
-	CharacterOctetLength   *int
-	NumericPrecision       *int
-}
 
-func (tables *DBTables) AsModels() []Model {
-	models := make([]Model, 0, len(*tables))
-	typer := NewTypesMapping()
-
-	for name, columns := range *tables {
-		modelFields := make([]Field, 0, len(columns))
-
-		sort.Slice(columns, func(i, j int) bool {
-			return columns[i].OrdinalPosition < columns[j].OrdinalPosition
-		})
-
-		for _, col := range columns {
-			modelFields = append(modelFields, col.AsField(typer))
+func main() {
+	name := filepath.Base(os.Args[0])
+	args := os.Args[1:]
+
+	// without a command (or with flags only) the tool generates, as it
+	// always did
+	cmd := commands[0]
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			usage(os.Stdout, name)
+			return
+		case "-version", "--version":
+			args[0] = "version"
+		}
+		if !strings.HasPrefix(args[0], "-") {
+			c, ok := findCommand(args[0])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+				usage(os.Stderr, name)
+				os.Exit(2)
+			}
+			cmd, args = c, args[1:]
 		}
-
-		models = append(models, Model{
-			Name:      toCamelCase(name),
-			TableName: name,
-			Fields:    modelFields,
-		})
 	}
 
-	return models
+	// the first interrupt cancels ctx, a second one kills the process as
+	// usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := cmd.Run(ctx, name+" "+cmd.Name, args)
+	if err == errChanged {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-type Model struct {
-	Name      string
-	TableName string
-	Fields    []Field
+// generate introspects the database, or reads the schema from files, and
+// renders the files of cfg.Format.
+func generate(ctx context.Context, cfg *Config) ([]codegen.OutputFile, codegen.GenerationInfo, error) {
+	s, err := loadModels(ctx, cfg)
+	if err != nil {
+		return nil, codegen.GenerationInfo{}, err
+	}
+	files, err := s.render(cfg)
+	if err != nil {
+		return nil, codegen.GenerationInfo{}, err
+	}
+	return files, s.info, nil
 }
 
-type Field struct {
-	Name string
-	Type string
-	Tag  string
+// schemaModels are the models and enums built from a schema, what generate
+// renders.
+type schemaModels struct {
+	models  []codegen.Model
+	enums   []codegen.Enum
+	dbEnums introspect.DBEnums
+	info    codegen.GenerationInfo
 }
 
-func (col *DBColumn) AsField(typer Typer) Field {
-	var (
-		tag       string
-		fieldType string
-		f         Field
-	)
-
-	if col.IsNullable {
-		tag = fmt.Sprintf(`sql:"%s"`, col.ColumnName)
-		t, err := typer.GetType(col.UDTName)
-		if err != nil {
-			panic(err)
-		}
-		fieldType = fmt.Sprintf("*%s", t)
+// loadModels introspects the database, or reads the schema from files, and
+// builds the models of its tables.
+func loadModels(ctx context.Context, cfg *Config) (*schemaModels, error) {
+	start := time.Now()
+	db, err := openSource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	database := cfg.Connection.DatabaseName()
+	if cfg.offline() {
+		database = cfg.offlineSource()
+		report.Debug.Printf("read %s in %s", database, time.Since(start))
 	} else {
-		tag = fmt.Sprintf(`sql:"%s,notnull"`, col.ColumnName)
-		t, err := typer.GetType(col.UDTName)
-		if err != nil {
-			panic(err)
-		}
-		fieldType = t
+		report.Debug.Printf("connected to %s in %s", database, time.Since(start))
 	}
-	f.Tag = tag
-	f.Type = fieldType
-	f.Name = toCamelCase(col.ColumnName)
-
-	return f
-}
-
-type TypesMapping struct {
-	SQLTypes map[string][]string
-}
-
-type Typer interface {
-	GetType(string) (string, error)
-}
 
-func NewTypesMapping() *TypesMapping {
-	return &TypesMapping{
-		map[string][]string{
-			"bool":   {"bool"},
-			"string": {"varchar", "text", "uuid"},
-			"int":    {"int2", "int4", "int8"},
-			// "int64":       {"bigint"},
-			"time.Time":   {"timestamp", "date"},
-			"interface{}": {"jsonb", "json"},
-			"[]string":    {"_text", "_varchar", "tsvector"},
-			"[]int":       {"_int2", "_int4", "_int8"},
-		},
+	step := time.Now()
+	report.Steps.Start("introspecting tables", 0)
+	tables, err := db.Tables(ctx, cfg.Schemas)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (tm *TypesMapping) GetType(sqlType string) (string, error) {
-	for goType, sqlTypes := range tm.SQLTypes {
-		for _, t := range sqlTypes {
-			if t == sqlType {
-				return goType, nil
-			}
+	report.Steps.Finish()
+	tables = tables.Filter(cfg.IncludeTables, cfg.ExcludeTables)
+	comments, err := db.TableComments(ctx, cfg.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	tables = tables.SkipMarked(comments, cfg.NoGenMarker)
+	dbEnums, err := db.Enums(ctx, cfg.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	enums := codegen.Enums(dbEnums, codegen.EnumNaming{Style: cfg.EnumStyle, Names: cfg.EnumNames})
+	// configured types win over enums, which win over the built-in mapping
+	custom := typemap.New()
+	for sqlType, goType := range cfg.Types {
+		if !strings.Contains(sqlType, ".") {
+			custom.Override(sqlType, goType)
 		}
 	}
-	return "", errors.New("type not detected")
-}
-
-type DB struct {
-	*sql.DB
-}
-
-func MustNewDB(connStr string) *DB {
-	db, err := sql.Open("postgres", connStr)
+	generated := typemap.New()
+	codegen.AddEnums(generated, enums)
+	typer := typemap.Chain{custom, generated, db.Types()}
+	models := codegen.BuildModels(tables, typer)
+	codegen.ApplyNullTime(models, cfg.NullTime)
+	codegen.ApplyColumnDirectives(models)
+	codegen.ApplyColumnTypes(models, cfg.Types)
+	codegen.RenameFields(models, cfg.FieldNames)
+	codegen.MarkSoftDelete(models, cfg.SoftDelete)
+	codegen.MarkVersion(models, cfg.VersionColumns)
+	codegen.NameModels(models, codegen.NamingOptions{
+		Singular:         cfg.SingularNames,
+		StripPrefixes:    cfg.StripPrefixes,
+		StripSuffixes:    cfg.StripSuffixes,
+		PackagePrefixes:  cfg.PackagePrefixes,
+		Names:            cfg.TableNames,
+		SchemaCollisions: cfg.SchemaCollisions,
+	})
+	fks, err := db.ForeignKeys(ctx, cfg.Schemas)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return &DB{db}
-}
-
-func (db *DB) GetAllTables() DBTables {
-	q := `
-SELECT 
-	c.table_name, c.column_name, c.ordinal_position, c.column_default, bool(c.is_nullable), c.data_type, c.udt_name, 
-	c.character_maximum_length, c.character_octet_length, c.numeric_precision
-FROM 
-	information_schema.columns AS c 
-JOIN
-	information_schema.tables as t
-ON
-	t.table_name = c.table_name
-WHERE 
-	t.table_schema = 'public' AND t.table_type = 'BASE TABLE'
-ORDER BY 
-	c.table_name;
-`
-	tables := make(DBTables)
-	rows, err := db.Query(q)
+	codegen.LinkRelations(models, fks)
+	codegen.RenameRelations(models, cfg.RelationNames)
+	codegen.MarkHasMany(models, cfg.HasManyFields)
+	codegen.SortFields(models, cfg.FieldOrder)
+	codegen.ApplyJSONTypes(models, cfg.JSONTypes)
+	triggers, err := db.Triggers(ctx, cfg.Schemas)
 	if err != nil {
-		log.Fatal(err)
-	} else {
-		defer rows.Close()
+		return nil, err
 	}
-
-	for rows.Next() {
-		var (
-			tableName string
-			col       = new(DBColumn)
-		)
-		if err := rows.Scan(
-			&tableName, &col.ColumnName, &col.OrdinalPosition, &col.ColumnDefault, &col.IsNullable, &col.DataType,
-			&col.UDTName, &col.CharacterMaximumLength, &col.CharacterOctetLength, &col.NumericPrecision,
-		); err != nil {
-			log.Print(err)
-			continue
+	security, err := db.RowSecurity(ctx, cfg.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	for i := range models {
+		models[i].Comment = comments[models[i].Key()]
+		models[i].Triggers = triggers[models[i].Key()]
+		if rls, ok := security[models[i].Key()]; ok {
+			models[i].RowSecurity = &rls
 		}
-		if _, ok := tables[tableName]; !ok {
-			tables[tableName] = make([]DBColumn, 0, 1)
+	}
+	if models, err = codegen.RunModelHooks(cfg.Hooks.hooks(), models); err != nil {
+		return nil, err
+	}
+	report.Debug.Printf("introspected %d tables and %d enums in %s", len(models), len(enums), time.Since(step))
+	for _, m := range models {
+		report.Debug.Printf("table %s: model %s", m.Key(), m.Name)
+		for _, f := range m.Fields {
+			goType := f.Type
+			if goType == "" {
+				goType = "(no mapping)"
+			}
+			report.Debug.Printf("  column %s %s: %s", f.Column.ColumnName, f.Column.UDTName, goType)
 		}
-		tables[tableName] = append(tables[tableName], *col)
 	}
 
-	return tables
+	info := codegen.GenerationInfo{
+		Generator: buildVersion(),
+		Database:  database,
+		Schema:    strings.Join(cfg.Schemas, ","),
+		Command:   append([]string{filepath.Base(os.Args[0])}, redactArgs(os.Args[1:], "p", "dsn")...),
+		Source: &introspect.Schema{
+			Version:     introspect.SnapshotVersion,
+			Dialect:     cfg.Connection.Dialect,
+			Schemas:     cfg.Schemas,
+			Tables:      tables,
+			ForeignKeys: fks,
+			Comments:    comments,
+			Enums:       dbEnums,
+			Triggers:    triggers,
+			RowSecurity: security,
+		},
+	}
+	return &schemaModels{models: models, enums: enums, dbEnums: dbEnums, info: info}, nil
 }
 
-func main() {
+// render renders the files of cfg.Format.
+func (s *schemaModels) render(cfg *Config) ([]codegen.OutputFile, error) {
+	models, enums, dbEnums := s.models, s.enums, s.dbEnums
+	step := time.Now()
+	report.Steps.Start("rendering models", len(models))
 	var (
-		separateFiles bool
-		configPath    string
-		username      string
-		password      string
-		database      string
-		sslMode       string
+		files   []codegen.OutputFile
+		content []byte
+		err     error
 	)
-	flag.BoolVar(&separateFiles, "sf", false, "generate separate file for each model")
-	flag.StringVar(&username, "u", "test", "username")
-	flag.StringVar(&password, "p", "test", "password")
-	flag.StringVar(&database, "d", "test", "database")
-	flag.StringVar(&sslMode, "ssl", "disable", "ssl mode")
-	flag.StringVar(&configPath, "c", "config", "path to config file")
-	flag.Parse()
-
-	db := MustNewDB(fmt.Sprintf(
-		"user=%s password=%s database=%s sslmode=%s",
-		username, password, database, sslMode,
-	))
-
-	tables := db.GetAllTables()
-	models := tables.AsModels()
-	// model := models[0]
-	modelFile, err := os.Create("models/models.go")
-	if err != nil {
-		log.Fatal(err)
-	}
-	var buffer bytes.Buffer
-	buf := bufio.NewWriter(&buffer)
-	buf.WriteString(headerTpl)
-	buf.WriteString("\n")
-
-	for _, model := range models {
-		tmpl, err := template.New("test").Parse(modelTpl)
-		if err != nil {
-			log.Fatal(err)
+	switch cfg.Format {
+	case "go":
+		files, err = codegen.RenderGo(models, enums, cfg.Go)
+	case "proto":
+		content, err = codegen.RenderProto(models, dbEnums)
+		files = []codegen.OutputFile{{Name: "models.proto", Content: content}}
+	case "graphql":
+		content, err = codegen.RenderGraphQL(models, dbEnums)
+		files = []codegen.OutputFile{{Name: "schema.graphql", Content: content}}
+	case "ts":
+		content, err = codegen.RenderTypeScript(models, dbEnums)
+		files = []codegen.OutputFile{{Name: "models.ts", Content: content}}
+	case "jsonschema":
+		files, err = codegen.RenderJSONSchema(models, dbEnums)
+	case "avro":
+		files, err = codegen.RenderAvro(models, dbEnums)
+	case "erd":
+		ext := ".mmd"
+		if cfg.ERDStyle == "dot" {
+			ext = ".dot"
 		}
-
-		if err := tmpl.Execute(buf, model); err != nil {
-			log.Fatal(err)
+		content, err = codegen.RenderERD(models, cfg.ERDStyle)
+		files = []codegen.OutputFile{{Name: "erd" + ext, Content: content}}
+	case "markdown":
+		content, err = codegen.RenderMarkdown(models)
+		files = []codegen.OutputFile{{Name: "SCHEMA.md", Content: content}}
+	case "pack":
+		var pack *codegen.Pack
+		if pack, err = codegen.LoadPack(cfg.Templates); err == nil {
+			files, err = pack.Render(models, enums, cfg.Go)
 		}
+	default:
+		err = fmt.Errorf("unknown format %q", cfg.Format)
 	}
-
-	buf.Flush()
-	content, err := format.Source(buffer.Bytes())
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	modelFile.Write(content)
+	report.Steps.Finish()
+	report.Debug.Printf("rendered %d %s files in %s", len(files), cfg.Format, time.Since(step))
+	return files, nil
 }
 
-// utils
-func toCamelCase(in string) (out string) {
-	var toUpper bool
-	for i, char := range in {
-		if i == 0 || toUpper {
-			out += string(unicode.ToUpper(char))
-			toUpper = false
+// redactArgs masks the values of the given flags so that secrets don't end up
+// in generated files.
+func redactArgs(args []string, secret ...string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+	for i := 0; i < len(out); i++ {
+		name := strings.TrimLeft(out[i], "-")
+		if !strings.HasPrefix(out[i], "-") {
 			continue
 		}
-		if char == '_' {
-			toUpper = true
-			continue
+		for _, s := range secret {
+			switch {
+			case name == s && i+1 < len(out):
+				out[i+1] = "***"
+				i++
+			case strings.HasPrefix(name, s+"="):
+				out[i] = out[i][:strings.Index(out[i], "=")+1] + "***"
+			}
+		}
+	}
+	return out
+}
+
+func splitList(in string) []string {
+	var out []string
+	for _, item := range strings.Split(in, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
 		}
-		out += string(char)
 	}
-	out = strings.NewReplacer(
-		"Id", "ID",
-		"Uuid", "UUID",
-		"Url", "URL",
-		"Html", "HTML",
-	).Replace(out)
-	return
+	return out
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serviceFilePaths returns the per-user and the system-wide service file, in
+// the order libpq consults them.
+func serviceFilePaths() []string {
+	var paths []string
+	if path := os.Getenv("PGSERVICEFILE"); path != "" {
+		paths = append(paths, path)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".pg_service.conf"))
+	}
+	if dir := os.Getenv("PGSYSCONFDIR"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "pg_service.conf"))
+	}
+	return paths
+}
+
+// ApplyService sets the connection settings of the named service from the
+// first service file defining it.
+func (c *ConnectionConfig) ApplyService(name string) error {
+	for _, path := range serviceFilePaths() {
+		settings, err := readServiceFile(path, name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if settings == nil {
+			continue
+		}
+
+		for key, value := range map[string]*string{
+			"host":        &c.Host,
+			"port":        &c.Port,
+			"user":        &c.User,
+			"password":    &c.Password,
+			"dbname":      &c.Database,
+			"sslmode":     &c.SSLMode,
+			"sslrootcert": &c.SSLRootCert,
+			"sslcert":     &c.SSLCert,
+			"sslkey":      &c.SSLKey,
+		} {
+			if v, ok := settings[key]; ok {
+				*value = v
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("service %q not found in %s", name, strings.Join(serviceFilePaths(), ", "))
+}
+
+// readServiceFile returns the key/value pairs of the [name] section, or nil
+// if there is no such section.
+func readServiceFile(path, name string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		settings map[string]string
+		inside   bool
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			if inside {
+				return settings, nil
+			}
+			inside = line[1:len(line)-1] == name
+			if inside {
+				settings = make(map[string]string)
+			}
+		case inside:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("%s: invalid line %q", path, line)
+			}
+			settings[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return settings, scanner.Err()
+}
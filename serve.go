@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+// serveFormats maps the file names served to the format rendering them,
+// longest suffix first.
+var serveFormats = []struct {
+	suffix, format, erdStyle string
+}{
+	{".schema.json", "jsonschema", ""},
+	{".avsc", "avro", ""},
+	{".go", "go", ""},
+	{".proto", "proto", ""},
+	{".graphql", "graphql", ""},
+	{".ts", "ts", ""},
+	{".md", "markdown", ""},
+	{".mmd", "erd", "mermaid"},
+	{".dot", "erd", "dot"},
+}
+
+func init() {
+	commands = append(commands, &Command{
+		Name:    "serve",
+		Summary: "serve generated files over http, e.g. GET /models.go?schema=public&tables=users",
+		Flags:   serveFlags,
+		Run:     runServe,
+	})
+}
+
+var serveAddr string
+
+func serveFlags(cfg *Config, fs *flag.FlagSet) {
+	outputFlags(cfg, fs)
+	fs.StringVar(&serveAddr, "addr", "localhost:8080", "address to listen on")
+}
+
+func runServe(ctx context.Context, name string, args []string) error {
+	cfg, err := loadConfig(name, args, serveFlags)
+	if err != nil {
+		return err
+	}
+	// requests render concurrently, a progress line would be garbage
+	report.Steps.SetOutput(ioutil.Discard)
+
+	if !cfg.Quiet {
+		log.Printf("serving models of %s on http://%s", cfg.Connection.DatabaseName(), serveAddr)
+	}
+	srv := &http.Server{Addr: serveAddr, Handler: &modelServer{cfg: cfg}}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// modelServer renders the requested file on every request, so it's always
+// up to date with the database. The query parameters schema and tables
+// (comma separated, patterns as in -include) override the config, go files
+// are always rendered into a single file.
+type modelServer struct {
+	cfg *Config
+}
+
+func (s *modelServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := *s.cfg
+	cfg.Go.SeparateFiles = false
+	// files of packages below the output directory, e.g. billing/models.go,
+	// are served at their path relative to it
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	cfg.Format = ""
+	for _, f := range serveFormats {
+		if strings.HasSuffix(name, f.suffix) {
+			cfg.Format = f.format
+			if f.erdStyle != "" {
+				cfg.ERDStyle = f.erdStyle
+			}
+			break
+		}
+	}
+	if cfg.Format == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if cfg.Format == "go" {
+		cfg.Go.FileSuffix = ".go"
+	}
+
+	query := r.URL.Query()
+	if schemas := query.Get("schema"); schemas != "" {
+		cfg.Schemas = splitList(schemas)
+	}
+	if tables := query.Get("tables"); tables != "" {
+		cfg.IncludeTables = splitList(tables)
+	}
+	if err := cfg.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files, info, err := generate(r.Context(), &cfg)
+	if err != nil {
+		log.Printf("%s: %v", r.URL, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	info.Command = []string{r.Method, r.URL.RequestURI()}
+	for _, file := range files {
+		if file.Name != name {
+			continue
+		}
+		contentType := "text/plain; charset=utf-8"
+		if strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".avsc") {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(append(info.Header(file.Name), file.Content...))
+		return
+	}
+	http.Error(w, fmt.Sprintf("%s is not generated for the requested tables", name), http.StatusNotFound)
+}
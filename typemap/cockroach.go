@@ -0,0 +1,13 @@
+package typemap
+
+// NewCockroachTypesMapping returns the built-in mapping extended by the udt
+// names CockroachDB reports for its own types, e.g. int8 for INT and text
+// for STRING.
+func NewCockroachTypesMapping() *TypesMapping {
+	tm := NewTypesMapping()
+	tm.SQLTypes["time.Time"] = append(tm.SQLTypes["time.Time"], "timestamptz")
+	tm.SQLTypes["float64"] = append(tm.SQLTypes["float64"], "float4", "float8")
+	tm.SQLTypes["[]byte"] = append(tm.SQLTypes["[]byte"], "bytea")
+	tm.SQLTypes["[]string"] = append(tm.SQLTypes["[]string"], "_uuid")
+	return tm
+}
@@ -0,0 +1,16 @@
+package typemap
+
+// NewMSSQLTypesMapping returns the built-in mapping of SQL Server data types.
+func NewMSSQLTypesMapping() *TypesMapping {
+	return &TypesMapping{
+		Imports: make(map[string]string),
+		SQLTypes: map[string][]string{
+			"bool":      {"bit"},
+			"string":    {"char", "varchar", "text", "nchar", "nvarchar", "ntext", "uniqueidentifier", "xml"},
+			"int":       {"tinyint", "smallint", "int", "bigint"},
+			"float64":   {"real", "float"},
+			"time.Time": {"date", "datetime", "datetime2", "smalldatetime", "datetimeoffset"},
+			"[]byte":    {"binary", "varbinary", "image", "rowversion", "timestamp"},
+		},
+	}
+}
@@ -0,0 +1,20 @@
+package typemap
+
+// NewMySQLTypesMapping returns the built-in mapping of MySQL data types.
+// Unsigned integer columns are introspected as e.g. "int unsigned", tinyint(1)
+// as "bool".
+func NewMySQLTypesMapping() *TypesMapping {
+	return &TypesMapping{
+		Imports: make(map[string]string),
+		SQLTypes: map[string][]string{
+			"bool":        {"bool"},
+			"string":      {"char", "varchar", "tinytext", "text", "mediumtext", "longtext"},
+			"int":         {"tinyint", "smallint", "mediumint", "int", "bigint"},
+			"uint":        {"tinyint unsigned", "smallint unsigned", "mediumint unsigned", "int unsigned", "bigint unsigned"},
+			"float64":     {"float", "double"},
+			"time.Time":   {"date", "datetime", "timestamp"},
+			"[]byte":      {"binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob"},
+			"interface{}": {"json"},
+		},
+	}
+}
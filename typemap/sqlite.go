@@ -0,0 +1,19 @@
+package typemap
+
+// NewSQLiteTypesMapping returns the built-in mapping of SQLite column types,
+// which are the declared types without their length, e.g. "varchar" for
+// VARCHAR(255).
+func NewSQLiteTypesMapping() *TypesMapping {
+	return &TypesMapping{
+		Imports: make(map[string]string),
+		SQLTypes: map[string][]string{
+			"bool":        {"bool", "boolean"},
+			"string":      {"text", "char", "varchar", "clob", "uuid"},
+			"int":         {"integer", "int", "tinyint", "smallint", "mediumint", "bigint"},
+			"float64":     {"real", "double", "float"},
+			"time.Time":   {"date", "datetime", "timestamp"},
+			"[]byte":      {"blob"},
+			"interface{}": {"json"},
+		},
+	}
+}
@@ -0,0 +1,144 @@
+// Package typemap maps Postgres column types (udt names) to Go types.
+package typemap
+
+import (
+	"errors"
+	"strings"
+)
+
+// Revision is bumped whenever the built-in SQL to Go type mapping changes,
+// since that changes generated code without any schema change.
+const Revision = 5
+
+// ErrUnknownType is returned by a Typer that has no mapping for a type, a
+// Chain moves on to its next Typer then.
+var ErrUnknownType = errors.New("type not detected")
+
+type TypesMapping struct {
+	SQLTypes map[string][]string
+	// Imports are the import paths of Go types registered with one.
+	Imports map[string]string
+}
+
+// Typer maps a udt name to a Go type. Typers may also implement Importer.
+type Typer interface {
+	GetType(string) (string, error)
+}
+
+// Importer is implemented by Typers whose Go types need an import.
+type Importer interface {
+	Import(goType string) string
+}
+
+// New returns an empty mapping, to be chained before the built-in one.
+func New() *TypesMapping {
+	return &TypesMapping{
+		SQLTypes: make(map[string][]string),
+		Imports:  make(map[string]string),
+	}
+}
+
+// NewTypesMapping returns the built-in mapping. Numeric maps to string, which
+// keeps it exact, configured types can map it to a decimal type instead.
+func NewTypesMapping() *TypesMapping {
+	return &TypesMapping{
+		Imports: make(map[string]string),
+		SQLTypes: map[string][]string{
+			"bool":   {"bool"},
+			"string": {"varchar", "bpchar", "text", "uuid", "tsvector", "tsquery", "numeric"},
+			"int":    {"int2", "int4", "int8"},
+			// "int64":       {"bigint"},
+			"time.Time":   {"timestamp", "date"},
+			"interface{}": {"jsonb", "json"},
+			"[]string":    {"_text", "_varchar", "_bpchar", "_numeric"},
+			"[]int":       {"_int2", "_int4", "_int8"},
+			"[]float32":   {"vector"},
+		},
+	}
+}
+
+// Override maps sqlType to goType, replacing any built-in mapping.
+func (tm *TypesMapping) Override(sqlType, goType string) {
+	for t, sqlTypes := range tm.SQLTypes {
+		for i, st := range sqlTypes {
+			if st == sqlType {
+				tm.SQLTypes[t] = append(sqlTypes[:i:i], sqlTypes[i+1:]...)
+				break
+			}
+		}
+	}
+	tm.SQLTypes[goType] = append(tm.SQLTypes[goType], sqlType)
+}
+
+// Register maps sqlType to goType like Override, importPath is the package
+// goType is declared in, or empty for builtin and generated types.
+func (tm *TypesMapping) Register(sqlType, goType, importPath string) {
+	tm.Override(sqlType, goType)
+	if importPath != "" {
+		if tm.Imports == nil {
+			tm.Imports = make(map[string]string)
+		}
+		tm.Imports[strings.TrimLeft(goType, "*[]")] = importPath
+	}
+}
+
+// AddEnum maps the enum type sqlType, and arrays of it, to goType.
+func (tm *TypesMapping) AddEnum(sqlType, goType string) {
+	tm.SQLTypes[goType] = append(tm.SQLTypes[goType], sqlType)
+	tm.SQLTypes["[]"+goType] = append(tm.SQLTypes["[]"+goType], "_"+sqlType)
+}
+
+func (tm *TypesMapping) GetType(sqlType string) (string, error) {
+	for goType, sqlTypes := range tm.SQLTypes {
+		for _, t := range sqlTypes {
+			if t == sqlType {
+				return goType, nil
+			}
+		}
+	}
+	return "", ErrUnknownType
+}
+
+func (tm *TypesMapping) Import(goType string) string {
+	return tm.Imports[strings.TrimLeft(goType, "*[]")]
+}
+
+// Chain asks its Typers in order, e.g. custom mappings, then the built-in
+// one, then a Fallback.
+type Chain []Typer
+
+func (c Chain) GetType(sqlType string) (string, error) {
+	for _, t := range c {
+		goType, err := t.GetType(sqlType)
+		if err != ErrUnknownType {
+			return goType, err
+		}
+	}
+	return "", ErrUnknownType
+}
+
+// Import returns the import path of the first Typer knowing goType.
+func (c Chain) Import(goType string) string {
+	for _, t := range c {
+		if path := Import(t, goType); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// Fallback maps every type to the Go type it names, e.g.
+// Fallback("interface{}") at the end of a Chain.
+type Fallback string
+
+func (f Fallback) GetType(string) (string, error) {
+	return string(f), nil
+}
+
+// Import returns the import path of goType if t is an Importer.
+func Import(t Typer, goType string) string {
+	if i, ok := t.(Importer); ok {
+		return i.Import(goType)
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/asyndrige/postgres-model-generator/typemap"
+)
+
+// version and commit are set at build time with
+// -ldflags "-X main.version=... -X main.commit=...". Without them they are
+// taken from the module and VCS information embedded by the go command.
+var (
+	version = ""
+	commit  = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if version == "" {
+		version = "dev"
+		if ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+	if commit == "" && ok {
+		modified := false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if commit != "" && modified {
+			commit += "-dirty"
+		}
+	}
+}
+
+// buildVersion identifies the generator in generated-code headers.
+func buildVersion() string {
+	return fmt.Sprintf("%s (type map r%d)", version, typemap.Revision)
+}
+
+// versionInfo is the -version output.
+func versionInfo() string {
+	c := commit
+	if c == "" {
+		c = "unknown"
+	}
+	return fmt.Sprintf("postgres-model-generator %s\ncommit: %s\ntype map revision: %d\ngo: %s %s/%s\n",
+		version, c, typemap.Revision, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
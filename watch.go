@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/asyndrige/postgres-model-generator/internal/report"
+)
+
+// watch polls the schema hash every interval and calls regenerate when it
+// changes. Failed regenerations are reported but don't stop watching, the
+// next migration may well fix them. It returns once ctx is done.
+func watch(ctx context.Context, cfg *Config, interval time.Duration, regenerate func() error) error {
+	db, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	last, err := db.SchemaHash(ctx, cfg.Schemas)
+	if err != nil {
+		return err
+	}
+	if !cfg.Quiet {
+		log.Printf("watching schemas %v of %s for changes", cfg.Schemas, cfg.Connection.DatabaseName())
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		hash, err := db.SchemaHash(ctx, cfg.Schemas)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if hash == last {
+			continue
+		}
+		last = hash
+		report.Debug.Printf("schema changed, hash %s", hash)
+		if err := regenerate(); err != nil {
+			log.Print(err)
+		}
+	}
+}